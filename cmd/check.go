@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gerryqd/yacd/database"
+	"github.com/gerryqd/yacd/utils/diagutil"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+	"github.com/spf13/cobra"
+)
+
+// CheckConfig holds the options of the check command
+type CheckConfig struct {
+	*Config
+
+	// Whether to skip checking that include directories exist
+	SkipIncludeDirs bool
+}
+
+// newCheckCmd creates the check command
+func newCheckCmd(config *Config) *cobra.Command {
+	checkConfig := &CheckConfig{Config: config}
+
+	cmd := &cobra.Command{
+		Use:   "check [compile_commands.json]",
+		Short: "Validate an existing compilation database",
+		Long: `Validate a compilation database against the JSON compilation database
+specification and the filesystem.
+
+Errors are reported for missing required fields, entries with both "command"
+and "arguments", unresolvable directories, missing source files and duplicate
+files. Missing include directories are reported as warnings. The command exits
+with a non-zero status when any error is found.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "compile_commands.json"
+			if len(args) > 0 {
+				path = args[0]
+			}
+			return checkConfig.run(path)
+		},
+	}
+
+	cmd.Flags().BoolVar(&checkConfig.SkipIncludeDirs, "skip-include-dirs", false, "Do not check that include directories exist")
+	return cmd
+}
+
+// run loads a database and reports problems with its entries
+func (c *CheckConfig) run(path string) error {
+	db, err := database.Load(path)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := database.Check(db, database.CheckOptions{
+		DatabaseDir:      pathutil.GetDirectoryFromPath(path),
+		CheckIncludeDirs: !c.SkipIncludeDirs,
+	})
+
+	for _, diagnostic := range diagnostics {
+		diagutil.Print(diagnostic)
+	}
+
+	errorCount := diagutil.CountErrors(diagnostics)
+	warningCount := len(diagnostics) - errorCount
+
+	if errorCount > 0 {
+		return errorutil.NewErrorf("%s: %d errors and %d warnings in %d entries", path, errorCount, warningCount, len(db))
+	}
+	fmt.Println(strings.Repeat("-", 50))
+	if warningCount > 0 {
+		fmt.Printf("\033[33mWarning: %d warnings in %s\033[0m\n", warningCount, path)
+	}
+	fmt.Printf("\033[32m%s: %d entries checked, no errors\033[0m\n", path, len(db))
+	fmt.Println(strings.Repeat("-", 50))
+	return nil
+}
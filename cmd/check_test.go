@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckCmd(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "main.c"), []byte("int main(void) { return 0; }\n"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		expectError bool
+	}{
+		{
+			name:        "Valid database",
+			content:     fmt.Sprintf(`[{"directory": %q, "command": "gcc -c main.c", "file": "main.c"}]`, tempDir),
+			expectError: false,
+		},
+		{
+			name:        "Missing include directory is only a warning",
+			content:     fmt.Sprintf(`[{"directory": %q, "arguments": ["gcc", "-Imissing", "-c", "main.c"], "file": "main.c"}]`, tempDir),
+			expectError: false,
+		},
+		{
+			name:        "Missing directory",
+			content:     `[{"directory": "", "command": "gcc -c main.c", "file": "main.c"}]`,
+			expectError: true,
+		},
+		{
+			name:        "Missing source file",
+			content:     fmt.Sprintf(`[{"directory": %q, "command": "gcc -c other.c", "file": "other.c"}]`, tempDir),
+			expectError: true,
+		},
+		{
+			name:        "Not a database",
+			content:     `{"directory": "/project"}`,
+			expectError: true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbFile := filepath.Join(tempDir, fmt.Sprintf("db%d.json", i))
+			if err := os.WriteFile(dbFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test database: %v", err)
+			}
+
+			testCmd := NewRootCmd()
+			testCmd.SetArgs([]string{"check", dbFile})
+			err := testCmd.Execute()
+			if tt.expectError && err == nil {
+				t.Errorf("check expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("check unexpected error: %v", err)
+			}
+		})
+	}
+}
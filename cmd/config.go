@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gerryqd/yacd/config"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+	"github.com/spf13/pflag"
+)
+
+// loadConfigFile loads the configuration file given with --config, or the first
+// .yacd.yaml or .yacd.toml found walking up from the current directory
+func (c *GenerateConfig) loadConfigFile() (*config.File, string, error) {
+	if c.NoConfig {
+		return &config.File{}, "", nil
+	}
+
+	path := c.ConfigFile
+	if path == "" {
+		path = config.Find(pathutil.GetWorkingDirectory())
+		if path == "" {
+			return &config.File{}, "", nil
+		}
+	}
+
+	file, err := config.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, path, nil
+}
+
+// applyConfigFile fills in every option that was not given on the command line
+// from the configuration file
+func (c *GenerateConfig) applyConfigFile(flags *pflag.FlagSet, file *config.File, stdinHasData bool) error {
+	setString := func(name string, target *string, value string) {
+		if !flags.Changed(name) && value != "" {
+			*target = value
+		}
+	}
+	setBool := func(name string, target *bool, value bool) {
+		if !flags.Changed(name) && value {
+			*target = value
+		}
+	}
+	setStrings := func(name string, target *[]string, value []string) {
+		if !flags.Changed(name) && len(value) > 0 {
+			*target = value
+		}
+	}
+
+	setString("input-format", &c.InputFormat, file.InputFormat)
+	setString("output", &c.OutputFile, file.Output)
+	setBool("relative", &c.UseRelativePaths, file.Relative)
+	setString("base-dir", &c.BaseDir, file.BaseDir)
+	setString("directory", &c.Directory, file.Directory)
+	setBool("infer-directories", &c.InferDirectories, file.InferDirectories)
+	setString("path-style", &c.PathStyle, file.PathStyle)
+	setBool("resolve-symlinks", &c.ResolveSymlinks, file.ResolveSymlinks)
+	setBool("infer-headers", &c.InferHeaders, file.InferHeaders)
+	setBool("header-include-scan", &c.HeaderIncludeScan, file.HeaderIncludeScan)
+	setStrings("strip-regex", &c.StripPatterns, file.StripRegex)
+	setStrings("compiler", &c.Compilers, file.Compilers)
+	setStrings("wrapper", &c.Wrappers, file.Wrappers)
+	setStrings("include", &c.Includes, file.Include)
+	setStrings("exclude", &c.Excludes, file.Exclude)
+	setStrings("remove-flag", &c.RemoveFlags, file.RemoveFlags)
+	setBool("vars-from-env", &c.VariablesFromEnv, file.VariablesFromEnv)
+	c.FlagRules = file.FlagRules
+	setString("output-format", &c.OutputFormat, file.OutputFormat)
+
+	// Make settings in the file only apply when no log is given on the command line
+	if !flags.Changed("input") && !flags.Changed("kbuild-dir") && !stdinHasData {
+		setString("dry-run", &c.MakeCommand, file.MakeCommand)
+		setString("chdir", &c.MakeDirectory, file.MakeDirectory)
+		setStrings("env", &c.MakeEnv, file.Env)
+		setString("env-file", &c.MakeEnvFile, file.EnvFile)
+		setBool("verbose-build", &c.VerboseBuild, file.VerboseBuild)
+	}
+
+	variables, err := ParseVariables(c.VarArgs)
+	if err != nil {
+		return err
+	}
+	c.Variables = make(map[string]string, len(file.Variables)+len(variables))
+	for name, value := range file.Variables {
+		c.Variables[name] = value
+	}
+	for name, value := range variables {
+		c.Variables[name] = value
+	}
+
+	if flags.Changed("path-map") {
+		pathMaps, err := ParsePathMaps(c.PathMapArgs)
+		if err != nil {
+			return err
+		}
+		c.PathMaps = pathMaps
+	} else {
+		c.PathMaps = file.PathMaps
+	}
+
+	return nil
+}
+
+// effectiveConfig returns the merged command line and file configuration. Flag editing
+// options are folded into the flag rules they produce.
+func (c *GenerateConfig) effectiveConfig() (*config.File, error) {
+	flagRules, err := c.buildFlagRules()
+	if err != nil {
+		return nil, err
+	}
+
+	return &config.File{
+		InputFormat:       c.InputFormat,
+		Output:            c.OutputFile,
+		OutputFormat:      c.OutputFormat,
+		Relative:          c.UseRelativePaths,
+		BaseDir:           c.BaseDir,
+		Directory:         c.Directory,
+		InferDirectories:  c.InferDirectories,
+		PathStyle:         c.PathStyle,
+		ResolveSymlinks:   c.ResolveSymlinks,
+		InferHeaders:      c.InferHeaders,
+		HeaderIncludeScan: c.HeaderIncludeScan,
+		StripRegex:        c.StripPatterns,
+		Compilers:         c.Compilers,
+		Wrappers:          c.Wrappers,
+		PathMaps:          c.PathMaps,
+		Variables:         c.Variables,
+		VariablesFromEnv:  c.VariablesFromEnv,
+		Include:           c.Includes,
+		Exclude:           c.Excludes,
+		FlagRules:         flagRules,
+		MakeCommand:       c.MakeCommand,
+		MakeDirectory:     c.MakeDirectory,
+		Env:               c.MakeEnv,
+		EnvFile:           c.MakeEnvFile,
+		VerboseBuild:      c.VerboseBuild,
+	}, nil
+}
+
+// PrintConfig writes the effective configuration as YAML, noting the file it was loaded from
+func PrintConfig(w io.Writer, file *config.File, path string) error {
+	data, err := file.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if path != "" {
+		fmt.Fprintf(w, "# Configuration file: %s\n", path)
+	} else {
+		fmt.Fprintln(w, "# No configuration file found")
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ParsePathMaps parses FROM=TO path prefix rewrites
+func ParsePathMaps(values []string) ([]types.PathMap, error) {
+	var pathMaps []types.PathMap
+	for _, value := range values {
+		from, to, found := strings.Cut(value, "=")
+		if !found || from == "" {
+			return nil, errorutil.CreateInvalidArgumentError(value, "path map must have the form FROM=TO")
+		}
+		pathMaps = append(pathMaps, types.PathMap{From: from, To: to})
+	}
+	return pathMaps, nil
+}
+
+// ParseVariables parses NAME=VALUE variable assignments
+func ParseVariables(values []string) (map[string]string, error) {
+	variables := make(map[string]string, len(values))
+	for _, value := range values {
+		name, variable, found := strings.Cut(value, "=")
+		if !found || name == "" {
+			return nil, errorutil.CreateInvalidArgumentError(value, "variable must have the form NAME=VALUE")
+		}
+		variables[name] = variable
+	}
+	return variables, nil
+}
+
+// BuildVariables returns the variable table used to expand references in logs: the
+// given environment, then the make command environment, then the given variables,
+// later entries taking precedence
+func BuildVariables(env, makeEnv []string, variables map[string]string) map[string]string {
+	table := make(map[string]string)
+	for _, assignment := range append(append([]string(nil), env...), makeEnv...) {
+		if name, value, found := strings.Cut(assignment, "="); found && name != "" {
+			table[name] = value
+		}
+	}
+	for name, value := range variables {
+		table[name] = value
+	}
+	return table
+}
+
+// buildFlagRules returns the flag editing rules from the configuration file followed
+// by the rules given on the command line
+func (c *GenerateConfig) buildFlagRules() ([]types.FlagRule, error) {
+	rules := append([]types.FlagRule(nil), c.FlagRules...)
+
+	global := types.FlagRule{
+		Remove:          c.RemoveFlags,
+		RemoveWithValue: c.RemoveFlagsWithValue,
+		Prepend:         c.PrependFlags,
+		Append:          c.AppendFlags,
+	}
+	if len(global.Remove)+len(global.RemoveWithValue)+len(global.Prepend)+len(global.Append) > 0 {
+		rules = append(rules, global)
+	}
+
+	for _, spec := range c.ReplaceFlags {
+		rule, err := ParseReplaceRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// ParseReplaceRule parses a sed-style /REGEX/REPLACEMENT/ edit; any character may be used
+// as the delimiter, as in |-std=gnu(\d+)|-std=c$1|
+func ParseReplaceRule(spec string) (types.FlagRule, error) {
+	if len(spec) < 3 {
+		return types.FlagRule{}, errorutil.CreateInvalidArgumentError(spec, "replacement must have the form /REGEX/REPLACEMENT/")
+	}
+
+	delimiter := spec[:1]
+	parts := strings.Split(strings.TrimSuffix(spec[1:], delimiter), delimiter)
+	if len(parts) != 2 || parts[0] == "" {
+		return types.FlagRule{}, errorutil.CreateInvalidArgumentError(spec, "replacement must have the form /REGEX/REPLACEMENT/")
+	}
+	if _, err := regexp.Compile(parts[0]); err != nil {
+		return types.FlagRule{}, errorutil.CreateInvalidArgumentError(spec, err.Error())
+	}
+
+	return types.FlagRule{Replace: parts[0], With: parts[1]}, nil
+}
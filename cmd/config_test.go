@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestPrintConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, ".yacd.yaml")
+	content := "relative: true\nbase_dir: .\ncompilers: [xc8-cc]\nremove_flags: [-Werror]\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "File values",
+			args:     []string{"--config", configPath},
+			contains: []string{"# Configuration file: " + configPath, "relative: true", "base_dir: " + tempDir, "- xc8-cc", "- -Werror"},
+		},
+		{
+			name:        "Flags override the file",
+			args:        []string{"--config", configPath, "--relative=false", "--compiler", "picc", "--path-map", "/build=/src"},
+			contains:    []string{"- picc", "from: /build", "to: /src"},
+			notContains: []string{"relative: true", "xc8-cc"},
+		},
+		{
+			name:        "No configuration",
+			args:        []string{"--no-config"},
+			contains:    []string{"# No configuration file found", "output: compile_commands.json"},
+			notContains: []string{"compilers"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			testCmd := NewRootCmd()
+			testCmd.SetOut(&out)
+			testCmd.SetArgs(append([]string{"generate", "--print-config"}, tt.args...))
+
+			if err := testCmd.Execute(); err != nil {
+				t.Fatalf("print-config unexpected error: %v", err)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("print-config output %q does not contain %q", out.String(), expected)
+				}
+			}
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(out.String(), unexpected) {
+					t.Errorf("print-config output %q contains %q", out.String(), unexpected)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateWithConfigFile(t *testing.T) {
+	tempDir := t.TempDir()
+	inputFilePath := filepath.Join(tempDir, "build.log")
+	configPath := filepath.Join(tempDir, ".yacd.toml")
+
+	log := `make: Entering directory '/build/src'
+ccache gcc -Werror -fprofile-arcs -I/build/src/inc -c main.c -o main.o
+xc8-cc -c third_party/zlib.c -o zlib.o
+make: Leaving directory '/build/src'`
+	config := `output = "out/compile_commands.json"
+wrappers = ["ccache"]
+compilers = ["xc8-cc"]
+remove_flags = ["-Werror", "-fprofile-*"]
+exclude = ["third_party/**"]
+
+[[path_maps]]
+from = "/build/src"
+to = "/home/dev/project"
+`
+	if err := os.WriteFile(inputFilePath, []byte(log), 0644); err != nil {
+		t.Fatalf("Failed to create test input file: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "out"), 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"--config", configPath, "-i", inputFilePath})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	// The output path is relative to the configuration file
+	data, err := os.ReadFile(filepath.Join(tempDir, "out", "compile_commands.json"))
+	if err != nil {
+		t.Fatalf("Output file not created: %v", err)
+	}
+
+	var db types.CompilationDatabase
+	if err := json.Unmarshal(data, &db); err != nil {
+		t.Fatalf("Failed to parse output file: %v", err)
+	}
+	if len(db) != 1 {
+		t.Fatalf("Output has %d entries, expected 1: %v", len(db), db)
+	}
+	if db[0].Directory != "/home/dev/project" {
+		t.Errorf("Directory = %q, expected path map to apply", db[0].Directory)
+	}
+	if strings.Contains(db[0].Command, "ccache") || strings.Contains(db[0].Command, "-Werror") || strings.Contains(db[0].Command, "-fprofile") {
+		t.Errorf("Command = %q, expected wrapper and removed flags to be gone", db[0].Command)
+	}
+	if !strings.Contains(db[0].Command, "-I/home/dev/project/inc") {
+		t.Errorf("Command = %q, expected include path to be mapped", db[0].Command)
+	}
+}
+
+func TestParsePathMaps(t *testing.T) {
+	pathMaps, err := ParsePathMaps([]string{"/build=/src", "/a=/b=c"})
+	if err != nil {
+		t.Fatalf("ParsePathMaps() unexpected error: %v", err)
+	}
+	expected := []types.PathMap{{From: "/build", To: "/src"}, {From: "/a", To: "/b=c"}}
+	if len(pathMaps) != len(expected) || pathMaps[0] != expected[0] || pathMaps[1] != expected[1] {
+		t.Errorf("ParsePathMaps() = %v, expected %v", pathMaps, expected)
+	}
+
+	for _, invalid := range []string{"/build", "=/src"} {
+		if _, err := ParsePathMaps([]string{invalid}); err == nil {
+			t.Errorf("ParsePathMaps(%q) expected error, got nil", invalid)
+		}
+	}
+}
+
+func TestParseVariables(t *testing.T) {
+	variables, err := ParseVariables([]string{"CC=arm-none-eabi-gcc", "CFLAGS=-O2 -DX=1", "EMPTY="})
+	if err != nil {
+		t.Fatalf("ParseVariables() unexpected error: %v", err)
+	}
+	if variables["CC"] != "arm-none-eabi-gcc" || variables["CFLAGS"] != "-O2 -DX=1" || len(variables) != 3 {
+		t.Errorf("ParseVariables() = %v", variables)
+	}
+	if _, err := ParseVariables([]string{"=value"}); err == nil {
+		t.Error("ParseVariables() expected error for a missing name")
+	}
+}
+
+func TestBuildVariables(t *testing.T) {
+	t.Setenv("YACD_TEST_INCDIR", "/env/include")
+	t.Setenv("YACD_TEST_CC", "gcc")
+
+	table := BuildVariables(os.Environ(), []string{"YACD_TEST_CC=clang"}, map[string]string{"YACD_TEST_BOARD": "nucleo"})
+	expected := map[string]string{
+		"YACD_TEST_INCDIR": "/env/include",
+		"YACD_TEST_CC":     "clang",
+		"YACD_TEST_BOARD":  "nucleo",
+	}
+	for name, value := range expected {
+		if table[name] != value {
+			t.Errorf("BuildVariables()[%s] = %q, expected %q", name, table[name], value)
+		}
+	}
+
+	// Without an environment only the given variables are used
+	table = BuildVariables(nil, nil, map[string]string{"YACD_TEST_BOARD": "nucleo"})
+	if len(table) != 1 || table["YACD_TEST_BOARD"] != "nucleo" {
+		t.Errorf("BuildVariables() = %v, expected only YACD_TEST_BOARD", table)
+	}
+}
+
+// Variables are only taken from the environment of yacd with --vars-from-env
+func TestGenerateVariablesFromEnv(t *testing.T) {
+	t.Setenv("YACD_TEST_INCDIR", "/env/include")
+
+	tempDir := t.TempDir()
+	inputFilePath := filepath.Join(tempDir, "build.log")
+	outputFilePath := filepath.Join(tempDir, "compile_commands.json")
+	log := "make: Entering directory '/project'\ngcc -I$(YACD_TEST_INCDIR) -c main.c -o main.o\n"
+	if err := os.WriteFile(inputFilePath, []byte(log), 0644); err != nil {
+		t.Fatalf("Failed to create test input file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"Environment ignored", nil, "gcc -c /project/main.c -o main.o"},
+		{"Environment used", []string{"--vars-from-env"}, "gcc -I/env/include -c /project/main.c -o main.o"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testCmd := NewRootCmd()
+			testCmd.SetArgs(append([]string{"generate", "--no-config", "-i", inputFilePath, "-o", outputFilePath}, tt.args...))
+			if err := testCmd.Execute(); err != nil {
+				t.Fatalf("Failed to execute command: %v", err)
+			}
+
+			data, err := os.ReadFile(outputFilePath)
+			if err != nil {
+				t.Fatalf("Output file not created: %v", err)
+			}
+			var db types.CompilationDatabase
+			if err := json.Unmarshal(data, &db); err != nil {
+				t.Fatalf("Failed to parse output file: %v", err)
+			}
+			if len(db) != 1 || db[0].Command != tt.expected {
+				t.Errorf("Output = %+v, expected command %q", db, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfigMakeSettingsIgnoredWithInput(t *testing.T) {
+	tempDir := t.TempDir()
+	inputFilePath := filepath.Join(tempDir, "build.log")
+	outputFilePath := filepath.Join(tempDir, "compile_commands.json")
+	configPath := filepath.Join(tempDir, ".yacd.yaml")
+
+	if err := os.WriteFile(inputFilePath, []byte(testMakeLog), 0644); err != nil {
+		t.Fatalf("Failed to create test input file: %v", err)
+	}
+	config := "make_command: make all\nmake_directory: firmware\nenv: [V=1]\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"--config", configPath, "-i", inputFilePath, "-o", outputFilePath})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+	if _, err := os.Stat(outputFilePath); err != nil {
+		t.Errorf("Output file not created: %v", err)
+	}
+}
+
+func TestParseReplaceRule(t *testing.T) {
+	tests := []struct {
+		spec        string
+		expected    types.FlagRule
+		expectError bool
+	}{
+		{spec: "/-O[0-3]/-O0/", expected: types.FlagRule{Replace: "-O[0-3]", With: "-O0"}},
+		{spec: `|-std=gnu(\d+)|-std=c$1|`, expected: types.FlagRule{Replace: `-std=gnu(\d+)`, With: "-std=c$1"}},
+		{spec: "/-g//", expected: types.FlagRule{Replace: "-g", With: ""}},
+		{spec: "/-g/", expectError: true},
+		{spec: "//x/", expectError: true},
+		{spec: "/(/x/", expectError: true},
+		{spec: "/a/b/c/", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rule, err := ParseReplaceRule(tt.spec)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseReplaceRule(%q) expected error, got nil", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseReplaceRule(%q) unexpected error: %v", tt.spec, err)
+			}
+			if rule.Replace != tt.expected.Replace || rule.With != tt.expected.With {
+				t.Errorf("ParseReplaceRule(%q) = %+v, expected %+v", tt.spec, rule, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateWithFlagRules(t *testing.T) {
+	tempDir := t.TempDir()
+	inputFilePath := filepath.Join(tempDir, "build.log")
+	outputFilePath := filepath.Join(tempDir, "compile_commands.json")
+	configPath := filepath.Join(tempDir, ".yacd.yaml")
+
+	log := `make: Entering directory '/project'
+gcc -Werror -flto -include config.h -std=gnu11 -c src/main.c -o main.o
+gcc -Werror -c third_party/zlib.c -o zlib.o
+make: Leaving directory '/project'`
+	config := `flag_rules:
+  - files: "third_party/**"
+    append: [-w]
+`
+	if err := os.WriteFile(inputFilePath, []byte(log), 0644); err != nil {
+		t.Fatalf("Failed to create test input file: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{
+		"--config", configPath, "-i", inputFilePath, "-o", outputFilePath,
+		"--remove-flag", "-Werror", "--remove-flag", "-flto",
+		"--remove-flag-with-value", "-include",
+		"--prepend-flag", "-D__clang_analyzer__",
+		"--append-flag", "-Wno-unused",
+		"--replace-flag", `|^-std=gnu(\d+)$|-std=c$1|`,
+	})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFilePath)
+	if err != nil {
+		t.Fatalf("Output file not created: %v", err)
+	}
+	var db types.CompilationDatabase
+	if err := json.Unmarshal(data, &db); err != nil {
+		t.Fatalf("Failed to parse output file: %v", err)
+	}
+
+	expected := []string{
+		"gcc -D__clang_analyzer__ -std=c11 -c /project/src/main.c -o main.o -Wno-unused",
+		"gcc -D__clang_analyzer__ -c /project/third_party/zlib.c -o zlib.o -w -Wno-unused",
+	}
+	if len(db) != len(expected) {
+		t.Fatalf("Output has %d entries, expected %d", len(db), len(expected))
+	}
+	for i, entry := range db {
+		if entry.Command != expected[i] {
+			t.Errorf("Entry %d command = %q, expected %q", i, entry.Command, expected[i])
+		}
+	}
+}
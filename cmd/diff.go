@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerryqd/yacd/database"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/spf13/cobra"
+)
+
+// Diff output formats
+const (
+	diffFormatText = "text"
+	diffFormatJSON = "json"
+)
+
+// DiffConfig holds the options of the diff command
+type DiffConfig struct {
+	*Config
+
+	// Output format: text or json
+	Format string
+}
+
+// newDiffCmd creates the diff command
+func newDiffCmd(config *Config) *cobra.Command {
+	diffConfig := &DiffConfig{Config: config}
+
+	cmd := &cobra.Command{
+		Use:   "diff OLD NEW",
+		Short: "Show how two compilation databases differ",
+		Long: `Compare two compilation databases by source file and report the files added
+and removed, and for files in both, the flags added and removed, macros whose
+value changed, include directories searched in another order and other flags
+given in another order.
+
+Arguments are compared after splitting, so "command" and "arguments" entries
+compare equal, as do "-I inc" and "-Iinc". Output files and dependency-generation
+options are ignored. Relative directories are resolved against the location of
+each database.
+
+Usage examples:
+  yacd diff old/compile_commands.json compile_commands.json
+  yacd diff --format json before.json after.json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return diffConfig.run(cmd.OutOrStdout(), args[0], args[1])
+		},
+	}
+
+	cmd.Flags().StringVarP(&diffConfig.Format, "format", "f", diffFormatText, "Output format: text or json")
+	return cmd
+}
+
+// run prints the differences between two databases
+func (c *DiffConfig) run(out io.Writer, oldPath, newPath string) error {
+	if c.Format != diffFormatText && c.Format != diffFormatJSON {
+		return errorutil.CreateInvalidArgumentError("--format", fmt.Sprintf("unknown format %q, expected text or json", c.Format))
+	}
+
+	oldDB, err := loadRebased(oldPath)
+	if err != nil {
+		return err
+	}
+	newDB, err := loadRebased(newPath)
+	if err != nil {
+		return err
+	}
+
+	diff := database.Diff(oldDB, newDB)
+	if c.Format == diffFormatJSON {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return errorutil.WrapError(err, "failed to marshal diff to JSON")
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+
+	PrintDiff(out, diff)
+	return nil
+}
+
+// loadRebased loads a database and resolves its relative directories against its location
+func loadRebased(path string) (types.CompilationDatabase, error) {
+	db, err := database.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errorutil.WrapFileError(err, "resolve", path)
+	}
+	return database.Rebase(db, filepath.Dir(absPath)), nil
+}
+
+// PrintDiff prints a database diff as text
+func PrintDiff(out io.Writer, diff database.DatabaseDiff) {
+	for _, file := range diff.Added {
+		fmt.Fprintf(out, "\033[32m+ %s\033[0m\n", file)
+	}
+	for _, file := range diff.Removed {
+		fmt.Fprintf(out, "\033[31m- %s\033[0m\n", file)
+	}
+
+	for _, file := range diff.Changed {
+		fmt.Fprintf(out, "\033[33m~ %s\033[0m\n", file.File)
+		if file.Directory != nil {
+			fmt.Fprintf(out, "    directory: %s -> %s\n", file.Directory.Old, file.Directory.New)
+		}
+		if file.Compiler != nil {
+			fmt.Fprintf(out, "    compiler: %s -> %s\n", file.Compiler.Old, file.Compiler.New)
+		}
+		for _, flag := range file.AddedFlags {
+			fmt.Fprintf(out, "    \033[32m+ %s\033[0m\n", flag)
+		}
+		for _, flag := range file.RemovedFlags {
+			fmt.Fprintf(out, "    \033[31m- %s\033[0m\n", flag)
+		}
+		for _, define := range file.Defines {
+			fmt.Fprintf(out, "    define %s: %q -> %q\n", define.Name, define.Old, define.New)
+		}
+		if file.IncludeOrder != nil {
+			fmt.Fprintf(out, "    include order: %s -> %s\n", strings.Join(file.IncludeOrder.Old, " "), strings.Join(file.IncludeOrder.New, " "))
+		}
+		if file.Reordered != nil {
+			fmt.Fprintf(out, "    reordered: %s -> %s\n", strings.Join(file.Reordered.Old, " "), strings.Join(file.Reordered.New, " "))
+		}
+	}
+
+	if diff.Empty() {
+		fmt.Fprintln(out, "No differences")
+		return
+	}
+	fmt.Fprintf(out, "%d added, %d removed, %d changed\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/database"
+)
+
+func TestDiffCmd(t *testing.T) {
+	tempDir := t.TempDir()
+	oldFile := filepath.Join(tempDir, "old.json")
+	newFile := filepath.Join(tempDir, "new.json")
+
+	oldContent := `[
+  {"directory": "/project", "command": "gcc -DLEVEL=1 -O0 -c main.c -o main.o", "file": "main.c"},
+  {"directory": "/project", "command": "gcc -c old.c", "file": "old.c"}
+]`
+	newContent := `[
+  {"directory": "/project", "arguments": ["gcc", "-DLEVEL=2", "-O0", "-g", "-c", "main.c", "-o", "main.o"], "file": "main.c"},
+  {"directory": "/project", "arguments": ["gcc", "-c", "new.c"], "file": "new.c"}
+]`
+	if err := os.WriteFile(oldFile, []byte(oldContent), 0644); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := os.WriteFile(newFile, []byte(newContent), 0644); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	runDiff := func(args ...string) string {
+		var out strings.Builder
+		testCmd := NewRootCmd()
+		testCmd.SetOut(&out)
+		testCmd.SetErr(&out)
+		testCmd.SetArgs(append([]string{"diff"}, args...))
+		if err := testCmd.Execute(); err != nil {
+			t.Fatalf("diff unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	text := runDiff(oldFile, newFile)
+	for _, expected := range []string{"+ /project/new.c", "- /project/old.c", "~ /project/main.c", "+ -g", `define LEVEL: "1" -> "2"`, "1 added, 1 removed, 1 changed"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("diff output %q does not contain %q", text, expected)
+		}
+	}
+
+	var diff database.DatabaseDiff
+	if err := json.Unmarshal([]byte(runDiff("--format", "json", oldFile, newFile)), &diff); err != nil {
+		t.Fatalf("diff JSON output is invalid: %v", err)
+	}
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Changed) != 1 || diff.Changed[0].Defines[0].New != "2" {
+		t.Errorf("diff JSON = %+v", diff)
+	}
+
+	if text := runDiff(oldFile, oldFile); !strings.Contains(text, "No differences") {
+		t.Errorf("diff of a database with itself = %q, expected no differences", text)
+	}
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"diff", "-f", "xml", oldFile, newFile})
+	if err := testCmd.Execute(); err == nil {
+		t.Error("diff with an unknown format should fail")
+	}
+}
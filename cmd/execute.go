@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"strings"
 
@@ -13,36 +12,135 @@ import (
 	"github.com/gerryqd/yacd/utils/pathutil"
 )
 
-// ExecuteGeneration executes the generation process with the given options and reader
-func ExecuteGeneration(options *types.ParseOptions, reader io.Reader) error {
-	// Parse make log
-	entries, err := parser.ParseMakeLog(reader, options.Verbose)
+// ExecuteGeneration executes the generation process with the given options and inputs
+func ExecuteGeneration(options *types.ParseOptions, inputs []LogInput) error {
+	entries, err := ParseInputs(options, inputs)
 	if err != nil {
-		return errorutil.WrapParseError(err, "failed to parse make log")
+		return err
+	}
+	return GenerateFromEntries(options, entries)
+}
+
+// ParseInputs parses the kbuild command files or each log with its own directory stack
+func ParseInputs(options *types.ParseOptions, inputs []LogInput) ([]types.MakeLogEntry, error) {
+	var entries []types.MakeLogEntry
+	if options.KbuildDir != "" {
+		kbuildEntries, err := parseKbuildDir(options)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, kbuildEntries...)
+	}
+	var quiet *parser.QuietBuild
+	quietInput := ""
+	for _, input := range inputs {
+		inputEntries, inputQuiet, err := parseLogInput(options, input)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, inputEntries...)
+		if quiet == nil && inputQuiet != nil {
+			quiet, quietInput = inputQuiet, input.Name
+		}
 	}
 
+	// A log of quiet build output has no commands to parse
+	if len(entries) == 0 && quiet != nil {
+		return nil, QuietBuildError(quietInput, quiet)
+	}
+	return entries, nil
+}
+
+// GenerateFromEntries generates and writes the compilation database for parsed entries
+func GenerateFromEntries(options *types.ParseOptions, entries []types.MakeLogEntry) error {
 	// Generate compilation database
-	compilationDB, warningCount := generator.GenerateCompilationDatabase(entries, options)
+	compilationDB, warningCount, err := generator.GenerateCompilationDatabase(entries, options)
+	if err != nil {
+		return errorutil.WrapGenerationError(err, "compilation database")
+	}
 
 	// Write to file
-	if err := generator.WriteCompilationDatabase(compilationDB, options.OutputFile); err != nil {
+	if err := generator.WriteCompilationDatabase(compilationDB, options.OutputFile, options.OutputFormat); err != nil {
 		return errorutil.WrapFileError(err, "write compilation database to", options.OutputFile)
 	}
 
+	// Count entries whose working directory had to be inferred
+	inferredCount := 0
+	for _, entry := range entries {
+		if entry.DirectoryInferred {
+			inferredCount++
+		}
+	}
+
 	// Print summary with improved formatting
 	fmt.Println(strings.Repeat("-", 50))
 	if warningCount > 0 {
 		fmt.Printf("\033[33mWarning: %d entries have non-existent source files\033[0m\n", warningCount)
 	}
+	if inferredCount > 0 {
+		fmt.Printf("\033[33mNote: %d entries have an inferred working directory\033[0m\n", inferredCount)
+	}
 	fmt.Printf("\033[32mSuccessfully generated %s with %d entries\033[0m\n", options.OutputFile, len(compilationDB))
 	fmt.Println(strings.Repeat("-", 50))
 	return nil
 }
 
-// PrepareReader prepares the input reader based on options
-func PrepareReader(options types.ParseOptions, stdinHasData bool) (io.Reader, func(), error) {
-	var reader io.Reader
-	var cleanup func()
+// QuietBuildError explains that a log only holds quiet build output and how to get commands
+func QuietBuildError(inputName string, quiet *parser.QuietBuild) error {
+	return errorutil.NewError(fmt.Sprintf(
+		"no compile commands found in %s, it looks like quiet %s output (line %d: %q, quiet lines: %d); %s, or use --verbose-build with --dry-run",
+		inputName, quiet.Name, quiet.LineNumber, quiet.Line, quiet.Count, quiet.Hint))
+}
+
+// parseLogInput parses a single log in the input format with a fresh parser, also returning the quiet
+// build output seen in it
+func parseLogInput(options *types.ParseOptions, input LogInput) ([]types.MakeLogEntry, *parser.QuietBuild, error) {
+	inputOptions := *options
+	if input.IsFile {
+		inputOptions.InputFile = input.Name
+	}
+
+	if options.Verbose && input.IsFile {
+		fmt.Printf("Parsing input file: %s\n", input.Name)
+	}
+
+	logParser, err := parser.NewParser(inputOptions)
+	if err != nil {
+		return nil, nil, errorutil.WrapParseError(err, "failed to create parser")
+	}
+
+	entries, err := logParser.ParseInput(options.InputFormat, input.Reader)
+	if err != nil {
+		return nil, nil, errorutil.WrapParseError(err, input.Name)
+	}
+
+	return entries, logParser.QuietBuild(), nil
+}
+
+// parseKbuildDir parses the command files of a kbuild tree
+func parseKbuildDir(options *types.ParseOptions) ([]types.MakeLogEntry, error) {
+	if options.Verbose {
+		fmt.Printf("Parsing kbuild directory: %s\n", options.KbuildDir)
+	}
+
+	kbuildParser, err := parser.NewParser(*options)
+	if err != nil {
+		return nil, errorutil.WrapParseError(err, "failed to create parser")
+	}
+
+	entries, err := kbuildParser.ParseKbuildDir(options.KbuildDir)
+	if err != nil {
+		return nil, errorutil.WrapParseError(err, options.KbuildDir)
+	}
+	return entries, nil
+}
+
+// PrepareInputs prepares the input readers based on options
+func PrepareInputs(options types.ParseOptions, stdinHasData bool) ([]LogInput, func(), error) {
+	// Kbuild trees are read by ExecuteGeneration
+	if options.KbuildDir != "" {
+		return nil, func() {}, nil
+	}
 
 	// Handle make command execution
 	if options.MakeCommand != "" {
@@ -55,6 +153,12 @@ func PrepareReader(options types.ParseOptions, stdinHasData bool) (io.Reader, fu
 			return nil, nil, errorutil.WrapExecutionError(err, options.MakeCommand)
 		}
 
+		// Apply working directory and environment overrides
+		cmd.Dir = options.MakeDirectory
+		if len(options.MakeEnv) > 0 {
+			cmd.Env = append(os.Environ(), options.MakeEnv...)
+		}
+
 		// Execute command and get stdout reader
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
@@ -66,35 +170,50 @@ func PrepareReader(options types.ParseOptions, stdinHasData bool) (io.Reader, fu
 			return nil, nil, errorutil.WrapExecutionError(err, options.MakeCommand)
 		}
 
-		reader = stdout
-		cleanup = func() {
+		cleanup := func() {
 			cmd.Wait() // Wait for command to finish
 		}
-	} else if stdinHasData {
-		// Handle stdin input
+		return []LogInput{{Name: options.MakeCommand, Reader: stdout}}, cleanup, nil
+	}
+
+	// Handle stdin input
+	if stdinHasData {
 		if options.Verbose {
 			fmt.Printf("Reading from stdin\n")
 		}
-		reader = os.Stdin
-		cleanup = func() {} // No cleanup needed for stdin
-	} else {
-		// Handle input file
-		if _, err := os.Stat(options.InputFile); os.IsNotExist(err) {
-			return nil, nil, errorutil.CreateFileNotExistError(options.InputFile)
-		}
 
-		file, err := os.Open(options.InputFile)
+		reader, closeDecoder, err := newDecompressingReader(os.Stdin)
 		if err != nil {
-			return nil, nil, errorutil.WrapFileError(err, "open", options.InputFile)
+			return nil, nil, errorutil.WrapError(err, "failed to decompress stdin")
 		}
+		return []LogInput{{Name: "stdin", Reader: reader}}, closeDecoder, nil
+	}
+
+	// Handle input files
+	inputFiles := options.InputFiles
+	if len(inputFiles) == 0 {
+		inputFiles = []string{options.InputFile}
+	}
 
-		reader = file
-		cleanup = func() {
-			file.Close()
+	var inputs []LogInput
+	var cleanups []func()
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
 		}
 	}
 
-	return reader, cleanup, nil
+	for _, inputFile := range inputFiles {
+		reader, fileCleanup, err := OpenLogFile(inputFile)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		cleanups = append(cleanups, fileCleanup)
+		inputs = append(inputs, LogInput{Name: inputFile, IsFile: true, Reader: reader})
+	}
+
+	return inputs, cleanup, nil
 }
 
 // PrepareOptions prepares and validates parse options
@@ -124,3 +243,30 @@ func PrepareOptions(inputFile, outputFile, makeCommand, baseDir string,
 
 	return options, nil
 }
+
+// PrepareMakeOptions fills in the working directory and environment used to run the make command
+func PrepareMakeOptions(options *types.ParseOptions, makeDirectory string, env []string, envFile string) error {
+	if options.MakeCommand == "" {
+		if makeDirectory != "" || len(env) > 0 || envFile != "" {
+			return errorutil.NewError("--chdir, --env and --env-file can only be used with -n/--dry-run")
+		}
+		return nil
+	}
+
+	if makeDirectory != "" {
+		info, err := os.Stat(makeDirectory)
+		if err != nil || !info.IsDir() {
+			return errorutil.CreateInvalidArgumentError("--chdir", fmt.Sprintf("%s is not a directory", makeDirectory))
+		}
+	}
+
+	makeEnv, err := BuildMakeEnvironment(envFile, env)
+	if err != nil {
+		return err
+	}
+
+	options.MakeDirectory = makeDirectory
+	options.MakeEnv = makeEnv
+	options.RootDir = ResolveMakeRootDir(makeDirectory, options.MakeCommand)
+	return nil
+}
@@ -178,7 +178,7 @@ func TestPrepareOptionsWithCurrentDirectory(t *testing.T) {
 	}
 }
 
-func TestPrepareReaderValidation(t *testing.T) {
+func TestPrepareInputsValidation(t *testing.T) {
 	tests := []struct {
 		name          string
 		options       types.ParseOptions
@@ -222,31 +222,31 @@ func TestPrepareReaderValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			reader, cleanup, err := PrepareReader(tt.options, tt.stdinHasData)
+			inputs, cleanup, err := PrepareInputs(tt.options, tt.stdinHasData)
 
 			if tt.expectError {
 				if err == nil {
-					t.Errorf("PrepareReader() expected error, got nil")
+					t.Errorf("PrepareInputs() expected error, got nil")
 				} else if tt.errorContains != "" && !strings.Contains(err.Error(), tt.errorContains) {
-					t.Errorf("PrepareReader() error = %v, expected to contain %s", err, tt.errorContains)
+					t.Errorf("PrepareInputs() error = %v, expected to contain %s", err, tt.errorContains)
 				}
 				return
 			}
 
 			if err != nil {
 				// Some errors might be expected in test environment (e.g., missing make command)
-				t.Logf("PrepareReader() returned error (might be expected in test environment): %v", err)
+				t.Logf("PrepareInputs() returned error (might be expected in test environment): %v", err)
 				return
 			}
 
-			if reader == nil {
-				t.Errorf("PrepareReader() returned nil reader")
+			if len(inputs) == 0 || inputs[0].Reader == nil {
+				t.Errorf("PrepareInputs() returned no reader")
 				return
 			}
 
 			// Cleanup should not be nil
 			if cleanup == nil {
-				t.Errorf("PrepareReader() returned nil cleanup function")
+				t.Errorf("PrepareInputs() returned nil cleanup function")
 				return
 			}
 
@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/parser"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+	"github.com/spf13/cobra"
+)
+
+// GenerateConfig holds the options of the generate command
+type GenerateConfig struct {
+	*Config
+
+	// Input make log file paths or globs
+	InputFiles []string
+
+	// Format of the inputs, detected when auto
+	InputFormat string
+
+	// Output compile_commands.json file path
+	OutputFile string
+
+	// Whether to use relative paths
+	UseRelativePaths bool
+
+	// Base directory for relative paths
+	BaseDir string
+
+	// Working directory for entries without directory information
+	Directory string
+
+	// Whether to infer working directories when make does not print them
+	InferDirectories bool
+
+	// Whether to add entries for headers without their own entry
+	InferHeaders bool
+
+	// Whether to find headers by following #include directives
+	HeaderIncludeScan bool
+
+	// Whether to evaluate symbolic links in directories and files
+	ResolveSymlinks bool
+
+	// Style drive paths are written in
+	PathStyle string
+
+	// Additional regular expressions stripped from every log line
+	StripPatterns []string
+
+	// Additional compiler executable names to recognise
+	Compilers []string
+
+	// Launcher executables skipped before the compiler
+	Wrappers []string
+
+	// FROM=TO path prefix rewrites from the command line
+	PathMapArgs []string
+
+	// Path prefix rewrites
+	PathMaps []types.PathMap
+
+	// NAME=VALUE variable assignments from the command line
+	VarArgs []string
+
+	// Values of variables referenced in logs
+	Variables map[string]string
+
+	// Whether variables referenced in logs are also looked up in the environment
+	VariablesFromEnv bool
+
+	// Rules keeping only matching entries
+	Includes []string
+
+	// Rules removing matching entries
+	Excludes []string
+
+	// Glob patterns of compiler flags removed from every entry
+	RemoveFlags []string
+
+	// Flags removed together with their value from every entry
+	RemoveFlagsWithValue []string
+
+	// Flags inserted after the compiler of every entry
+	PrependFlags []string
+
+	// Flags added at the end of every entry
+	AppendFlags []string
+
+	// /REGEX/REPLACEMENT/ edits applied to every argument
+	ReplaceFlags []string
+
+	// Scoped flag editing rules from the configuration file
+	FlagRules []types.FlagRule
+
+	// Output format of the compilation database
+	OutputFormat string
+
+	// Configuration file path, found automatically when empty
+	ConfigFile string
+
+	// Whether to ignore configuration files
+	NoConfig bool
+
+	// Whether to print the effective configuration and exit
+	PrintConfig bool
+
+	// Make command to execute
+	MakeCommand string
+
+	// Kbuild tree whose .o.cmd files are parsed
+	KbuildDir string
+
+	// Directory to execute the make command in
+	MakeDirectory string
+
+	// KEY=VAL assignments for the make command environment
+	MakeEnv []string
+
+	// File with KEY=VAL lines for the make command environment
+	MakeEnvFile string
+
+	// Whether to add the verbose build variables to the make command
+	VerboseBuild bool
+}
+
+// newGenerateCmd creates the generate command
+func newGenerateCmd(config *Config) *cobra.Command {
+	generateConfig := &GenerateConfig{Config: config}
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate compile_commands.json from a make log",
+		Long: `Generate compile_commands.json from make logs, a dry-run make invocation, stdin
+or the command files of a kbuild tree.
+
+Usage examples:
+  yacd generate -i build.log -o compile_commands.json
+  yacd generate -n "make clean all" --verbose
+  make -Bnkw | yacd generate -o compile_commands.json
+  yacd generate --kbuild-dir ~/src/linux
+  yacd generate -i build/.ninja_log
+  bazel aquery --output=jsonproto 'mnemonic("CppCompile", //...)' | yacd generate --directory "$(bazel info execution_root)"`,
+		Args: cobra.NoArgs,
+		RunE: generateConfig.run,
+	}
+
+	addGenerateFlags(cmd, generateConfig)
+	return cmd
+}
+
+// addGenerateFlags registers the generate options on a command
+func addGenerateFlags(cmd *cobra.Command, config *GenerateConfig) {
+	addLogFlags(cmd, config)
+
+	flags := cmd.Flags()
+	flags.StringVarP(&config.OutputFile, "output", "o", "compile_commands.json", "Output file path (default depends on --output-format)")
+	flags.StringVar(&config.OutputFormat, "output-format", generator.DefaultOutputFormat, "Output format: "+strings.Join(generator.OutputFormats(), ", "))
+	flags.BoolVarP(&config.UseRelativePaths, "relative", "r", false, "Use relative paths instead of absolute paths")
+	flags.StringVarP(&config.BaseDir, "base-dir", "b", "", "Base directory path (used with --relative)")
+	flags.StringVarP(&config.MakeCommand, "dry-run", "n", "", "Execute make command with -Bnkw flags and process output directly")
+	flags.StringVar(&config.KbuildDir, "kbuild-dir", "", "Parse the .o.cmd files of a Linux kernel or other kbuild tree instead of a make log")
+	flags.StringVar(&config.MakeDirectory, "chdir", "", "Directory to run the make command in (used with --dry-run)")
+	flags.StringArrayVar(&config.MakeEnv, "env", nil, "Set KEY=VAL in the make command environment, may be repeated (used with --dry-run)")
+	flags.StringVar(&config.MakeEnvFile, "env-file", "", "Read KEY=VAL lines for the make command environment from a file (used with --dry-run)")
+	flags.BoolVar(&config.VerboseBuild, "verbose-build", false, "Add "+strings.Join(VerboseBuildVariables, " ")+" to the make command so quiet builds print their commands (used with --dry-run)")
+	flags.BoolVar(&config.PrintConfig, "print-config", false, "Print the effective configuration and exit")
+
+	// Mark mutually exclusive parameters
+	cmd.MarkFlagsMutuallyExclusive("input", "dry-run", "kbuild-dir")
+}
+
+// addLogFlags registers the options that decide how logs are parsed and turned into
+// entries, shared by generate and merge
+func addLogFlags(cmd *cobra.Command, config *GenerateConfig) {
+	flags := cmd.Flags()
+	flags.StringArrayVarP(&config.InputFiles, "input", "i", nil, "Input make log file path or glob, may be repeated (gzip, zstd and xz are decompressed)")
+	flags.StringVar(&config.InputFormat, "input-format", parser.InputFormatAuto, "Input format: "+strings.Join(parser.InputFormats(), ", "))
+	flags.StringVar(&config.Directory, "directory", "", "Working directory for entries logged without directory information")
+	flags.BoolVar(&config.InferDirectories, "infer-directories", false, "Infer working directories from make -C lines and source files on disk")
+	flags.StringVar(&config.PathStyle, "path-style", "", "Write Windows drive paths as "+strings.Join(pathutil.PathStyles(), ", ")+" (e.g. msys: /c/work)")
+	flags.BoolVar(&config.ResolveSymlinks, "resolve-symlinks", false, "Resolve symbolic links in directories, files and include paths")
+	flags.BoolVar(&config.InferHeaders, "infer-headers", false, "Add entries for headers borrowing the flags of the closest translation unit")
+	flags.BoolVar(&config.HeaderIncludeScan, "header-include-scan", false, "Find headers by following #include directives (used with --infer-headers)")
+	flags.StringArrayVar(&config.StripPatterns, "strip-regex", nil, "Regular expression removed from every log line before parsing, may be repeated")
+	flags.StringArrayVar(&config.Compilers, "compiler", nil, "Additional compiler executable name to recognise, may be repeated")
+	flags.StringArrayVar(&config.Wrappers, "wrapper", nil, "Compiler launcher such as ccache to skip before the compiler, may be repeated")
+	flags.StringArrayVar(&config.VarArgs, "var", nil, "Expand $(NAME), ${NAME} and $NAME in logs to VALUE (NAME=VALUE), may be repeated")
+	flags.BoolVar(&config.VariablesFromEnv, "vars-from-env", false, "Also expand variables referenced in logs from the environment of yacd")
+	flags.StringArrayVar(&config.PathMapArgs, "path-map", nil, "Rewrite paths starting with FROM to start with TO (FROM=TO), may be repeated")
+	flags.StringArrayVar(&config.Includes, "include", nil, "Keep only entries matching [dir:|file:][re:]PATTERN (glob by default), may be repeated")
+	flags.StringArrayVar(&config.Excludes, "exclude", nil, "Remove entries matching [dir:|file:][re:]PATTERN (glob by default), may be repeated")
+	flags.StringArrayVar(&config.RemoveFlags, "remove-flag", nil, "Remove compiler arguments matching a glob such as -fprofile-*, may be repeated")
+	flags.StringArrayVar(&config.RemoveFlagsWithValue, "remove-flag-with-value", nil, "Remove a compiler flag together with its value (-include x), may be repeated")
+	flags.StringArrayVar(&config.PrependFlags, "prepend-flag", nil, "Insert a compiler argument right after the compiler, may be repeated")
+	flags.StringArrayVar(&config.AppendFlags, "append-flag", nil, "Add a compiler argument at the end, may be repeated")
+	flags.StringArrayVar(&config.ReplaceFlags, "replace-flag", nil, "Replace a regular expression in every compiler argument (/REGEX/REPLACEMENT/), may be repeated")
+
+	flags.StringVar(&config.ConfigFile, "config", "", "Configuration file (default: .yacd.yaml or .yacd.toml found walking up from the current directory)")
+	flags.BoolVar(&config.NoConfig, "no-config", false, "Ignore configuration files")
+	cmd.MarkFlagsMutuallyExclusive("config", "no-config")
+}
+
+// run executes the generation operation
+func (c *GenerateConfig) run(cmd *cobra.Command, args []string) error {
+	// Apply the configuration file below command line flags
+	stdinHasData := HasStdinData()
+	configFile, configPath, err := c.loadConfigFile()
+	if err != nil {
+		return err
+	}
+	if err := c.applyConfigFile(cmd.Flags(), configFile, stdinHasData); err != nil {
+		return err
+	}
+
+	// Validate the output format and pick its default file name
+	writer, err := generator.NewDatabaseWriter(c.OutputFormat)
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("output") && configFile.Output == "" {
+		c.OutputFile = writer.DefaultFileName()
+	}
+	if c.PathStyle != "" && !pathutil.IsValidPathStyle(c.PathStyle) {
+		return errorutil.CreateInvalidArgumentError(c.PathStyle, "unsupported path style, expected one of: "+strings.Join(pathutil.PathStyles(), ", "))
+	}
+	if !parser.IsValidInputFormat(c.InputFormat) {
+		return errorutil.CreateInvalidArgumentError(c.InputFormat, "unsupported input format, expected one of: "+strings.Join(parser.InputFormats(), ", "))
+	}
+
+	if c.PrintConfig {
+		effective, err := c.effectiveConfig()
+		if err != nil {
+			return err
+		}
+		return PrintConfig(cmd.OutOrStdout(), effective, configPath)
+	}
+	if c.Verbose && configPath != "" {
+		fmt.Printf("Using configuration file: %s\n", configPath)
+	}
+
+	// Check if no input is provided and show help instead of error
+	if len(c.InputFiles) == 0 && c.MakeCommand == "" && c.KbuildDir == "" && !stdinHasData {
+		// Show help information instead of error when no input is provided
+		cmd.Help()
+		return nil
+	}
+
+	// Expand input file globs
+	inputFiles, err := ExpandInputFiles(c.InputFiles)
+	if err != nil {
+		return err
+	}
+	inputFile := ""
+	if len(inputFiles) > 0 {
+		inputFile = inputFiles[0]
+	}
+
+	// Validate input sources
+	if c.KbuildDir != "" {
+		if stdinHasData {
+			return errorutil.NewError("multiple input sources provided, --kbuild-dir cannot be combined with stdin")
+		}
+	} else if err := ValidateInputSources(inputFile, c.MakeCommand, stdinHasData); err != nil {
+		return err
+	}
+
+	// Ask quiet build systems to print their commands
+	if c.VerboseBuild {
+		if c.MakeCommand == "" {
+			return errorutil.NewError("--verbose-build can only be used with -n/--dry-run")
+		}
+		c.MakeCommand = AddVerboseBuildVariables(c.MakeCommand)
+	}
+
+	// Prepare options
+	options, err := PrepareOptions(inputFile, c.OutputFile, c.MakeCommand, c.BaseDir, c.UseRelativePaths, c.Verbose)
+	if err != nil {
+		return err
+	}
+	options.InputFiles = inputFiles
+	options.KbuildDir = c.KbuildDir
+	if err := c.applyLogOptions(&options); err != nil {
+		return err
+	}
+	options.OutputFormat = c.OutputFormat
+
+	// Prepare make working directory and environment
+	if err := PrepareMakeOptions(&options, c.MakeDirectory, c.MakeEnv, c.MakeEnvFile); err != nil {
+		return err
+	}
+
+	// Variables are looked up in the make environment and the configuration
+	options.Variables = BuildVariables(c.environment(), options.MakeEnv, c.Variables)
+
+	// Prepare inputs
+	inputs, cleanup, err := PrepareInputs(options, stdinHasData)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Execute generation
+	return ExecuteGeneration(&options, inputs)
+}
+
+// applyLogOptions copies the options deciding how logs are parsed and turned into
+// entries to options
+func (c *GenerateConfig) applyLogOptions(options *types.ParseOptions) error {
+	flagRules, err := c.buildFlagRules()
+	if err != nil {
+		return err
+	}
+
+	options.InputFormat = c.InputFormat
+	options.Directory = c.Directory
+	options.InferDirectories = c.InferDirectories
+	options.StripPatterns = c.StripPatterns
+	options.InferHeaders = c.InferHeaders
+	options.HeaderIncludeScan = c.HeaderIncludeScan
+	options.ResolveSymlinks = c.ResolveSymlinks
+	options.PathStyle = c.PathStyle
+	options.Compilers = c.Compilers
+	options.Wrappers = c.Wrappers
+	options.PathMaps = c.PathMaps
+	options.Includes = c.Includes
+	options.Excludes = c.Excludes
+	options.FlagRules = flagRules
+	return nil
+}
+
+// environment returns the environment variables referenced in logs are looked up in,
+// empty unless --vars-from-env is given, so that output does not depend on the machine
+func (c *GenerateConfig) environment() []string {
+	if !c.VariablesFromEnv {
+		return nil
+	}
+	return os.Environ()
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Magic bytes of supported compression formats
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// LogInput is a single make log to be parsed with its own directory stack
+type LogInput struct {
+	// Name of the input (file path, make command or "stdin")
+	Name string
+
+	// Whether Name is a file path
+	IsFile bool
+
+	// Reader for the (decompressed) log content
+	Reader io.Reader
+}
+
+// ExpandInputFiles expands glob patterns in -i arguments into a de-duplicated file list.
+// Files keep the order of their arguments, with each pattern's matches sorted by name;
+// spellings of the same path such as a.log and ./a.log are listed once.
+func ExpandInputFiles(patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return nil, errorutil.CreateInvalidArgumentError(pattern, err.Error())
+			}
+			if len(matches) == 0 {
+				return nil, errorutil.NewErrorf("no input files match pattern: %s", pattern)
+			}
+		}
+
+		for _, match := range matches {
+			key, err := filepath.Abs(match)
+			if err != nil {
+				key = filepath.Clean(match)
+			}
+			if !seen[key] {
+				seen[key] = true
+				files = append(files, match)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// OpenLogFile opens a log file, transparently decompressing gzip, zstd and xz content
+// detected by its magic bytes
+func OpenLogFile(path string) (io.Reader, func(), error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil, errorutil.CreateFileNotExistError(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, errorutil.WrapFileError(err, "open", path)
+	}
+
+	reader, closeDecoder, err := newDecompressingReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, errorutil.WrapFileError(err, "decompress", path)
+	}
+
+	cleanup := func() {
+		closeDecoder()
+		file.Close()
+	}
+	return reader, cleanup, nil
+}
+
+// newDecompressingReader wraps reader with a decoder chosen by the leading magic bytes
+func newDecompressingReader(reader io.Reader) (io.Reader, func(), error) {
+	buffered := bufio.NewReader(reader)
+	header, _ := buffered.Peek(len(xzMagic))
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		decoder, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+		return decoder, func() { decoder.Close() }, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+		return decoder, decoder.Close, nil
+	case bytes.HasPrefix(header, xzMagic):
+		decoder, err := xz.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+		return decoder, func() {}, nil
+	default:
+		return buffered, func() {}, nil
+	}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func TestExpandInputFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.log", "b.log", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	files, err := ExpandInputFiles([]string{
+		filepath.Join(tempDir, "*.log"),
+		filepath.Join(tempDir, "a.log"),
+		// The same file spelt differently is only read once
+		tempDir + "/./a.log",
+		filepath.Join(tempDir, "c.txt"),
+	})
+	if err != nil {
+		t.Fatalf("ExpandInputFiles() unexpected error: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join(tempDir, "a.log"),
+		filepath.Join(tempDir, "b.log"),
+		filepath.Join(tempDir, "c.txt"),
+	}
+	if strings.Join(files, "|") != strings.Join(expected, "|") {
+		t.Errorf("ExpandInputFiles() = %v, expected %v", files, expected)
+	}
+
+	// Relative and absolute spellings of a file are the same input
+	originalDir, _ := os.Getwd()
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+	files, err = ExpandInputFiles([]string{"a.log", "./a.log", filepath.Join(tempDir, "a.log")})
+	if err != nil {
+		t.Fatalf("ExpandInputFiles() unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0] != "a.log" {
+		t.Errorf("ExpandInputFiles() = %v, expected [a.log]", files)
+	}
+
+	if _, err := ExpandInputFiles([]string{filepath.Join(tempDir, "*.gz")}); err == nil {
+		t.Errorf("ExpandInputFiles() expected error for pattern without matches")
+	}
+}
+
+func TestOpenLogFile(t *testing.T) {
+	const content = "gcc -c main.c -o main.o\n"
+	tempDir := t.TempDir()
+
+	compressors := map[string]func(io.Writer) (io.WriteCloser, error){
+		"plain.log": func(w io.Writer) (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		},
+		"build.log.gz": func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		"build.log.zst": func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		},
+		"build.log.xz": func(w io.Writer) (io.WriteCloser, error) {
+			return xz.NewWriter(w)
+		},
+		// Compression is detected by content, not by extension
+		"misnamed.log": func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+	}
+
+	for name, newWriter := range compressors {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := newWriter(&buf)
+			if err != nil {
+				t.Fatalf("Failed to create compressor: %v", err)
+			}
+			writer.Write([]byte(content))
+			writer.Close()
+
+			path := filepath.Join(tempDir, name)
+			if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			reader, cleanup, err := OpenLogFile(path)
+			if err != nil {
+				t.Fatalf("OpenLogFile() unexpected error: %v", err)
+			}
+			defer cleanup()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Failed to read log: %v", err)
+			}
+			if string(data) != content {
+				t.Errorf("OpenLogFile() content = %q, expected %q", data, content)
+			}
+		})
+	}
+
+	if _, _, err := OpenLogFile(filepath.Join(tempDir, "missing.log")); err == nil {
+		t.Errorf("OpenLogFile() expected error for missing file")
+	}
+}
+
+func TestExecuteGenerationMultipleInputs(t *testing.T) {
+	tempDir := t.TempDir()
+	outputFile := filepath.Join(tempDir, "compile_commands.json")
+
+	// The first log leaves its directory stack unbalanced; the second must not inherit it
+	inputs := []LogInput{
+		{
+			Name:   "first.log",
+			Reader: strings.NewReader("make: Entering directory '/project/app'\ngcc -c main.c -o main.o\n"),
+		},
+		{
+			Name:   "second.log",
+			Reader: strings.NewReader("make: Entering directory '/project/lib'\ngcc -c list.c -o list.o\n"),
+		},
+	}
+
+	options := types.ParseOptions{OutputFile: outputFile}
+	if err := ExecuteGeneration(&options, inputs); err != nil {
+		t.Fatalf("ExecuteGeneration() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+
+	var db types.CompilationDatabase
+	if err := json.Unmarshal(data, &db); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	if len(db) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(db))
+	}
+	if db[0].Directory != "/project/app" || db[1].Directory != "/project/lib" {
+		t.Errorf("Directories = %s, %s, expected /project/app, /project/lib", db[0].Directory, db[1].Directory)
+	}
+}
+
+// nopWriteCloser adds a no-op Close to a writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
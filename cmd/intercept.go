@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/intercept"
+	"github.com/gerryqd/yacd/parser"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/spf13/cobra"
+)
+
+// InterceptConfig holds the options of the intercept command
+type InterceptConfig struct {
+	*Config
+
+	// Output file path
+	OutputFile string
+
+	// Output format of the compilation database
+	OutputFormat string
+
+	// JSON-lines file keeping the recorded invocations, a temporary file when empty
+	RecordFile string
+
+	// Additional compiler executable names to intercept
+	Compilers []string
+
+	// Whether to use relative paths
+	UseRelativePaths bool
+
+	// Base directory for relative paths
+	BaseDir string
+}
+
+// newInterceptCmd creates the intercept command
+func newInterceptCmd(config *Config) *cobra.Command {
+	interceptConfig := &InterceptConfig{Config: config}
+
+	cmd := &cobra.Command{
+		Use:   "intercept [flags] -- BUILD COMMAND...",
+		Short: "Run a build and record every compiler it starts",
+		Long: `Run a build with yacd shims for the compilers first in PATH, record the arguments,
+working directory and environment of every compiler started, and generate a
+compilation database from them.
+
+This also sees compilers started by scripts, code generators and sub-builds, which
+never appear in a dry-run log. No root, ptrace or LD_PRELOAD is needed, but compilers
+started by absolute path bypass PATH and are not seen.
+
+Usage examples:
+  yacd intercept -- make -j8
+  yacd intercept --record build.jsonl -- ./build.sh
+  yacd intercept --compiler xc8-cc -- make`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: interceptConfig.run,
+	}
+
+	flags := cmd.Flags()
+	flags.SetInterspersed(false)
+	flags.StringVarP(&interceptConfig.OutputFile, "output", "o", "compile_commands.json", "Output file path (default depends on --output-format)")
+	flags.StringVar(&interceptConfig.OutputFormat, "output-format", generator.DefaultOutputFormat, "Output format: "+strings.Join(generator.OutputFormats(), ", "))
+	flags.StringVar(&interceptConfig.RecordFile, "record", "", "Keep the recorded compiler invocations in this JSON-lines file")
+	flags.StringArrayVar(&interceptConfig.Compilers, "compiler", nil, "Additional compiler executable name to intercept, may be repeated")
+	flags.BoolVarP(&interceptConfig.UseRelativePaths, "relative", "r", false, "Use relative paths instead of absolute paths")
+	flags.StringVarP(&interceptConfig.BaseDir, "base-dir", "b", "", "Base directory path (used with --relative)")
+	return cmd
+}
+
+// run executes the build with compiler shims and generates the database from the records
+func (c *InterceptConfig) run(cmd *cobra.Command, args []string) error {
+	if runtime.GOOS == "windows" {
+		return errorutil.CreateUnsupportedError("intercept on Windows")
+	}
+
+	writer, err := generator.NewDatabaseWriter(c.OutputFormat)
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("output") {
+		c.OutputFile = writer.DefaultFileName()
+	}
+
+	recordFile, removeRecordFile, err := c.createRecordFile()
+	if err != nil {
+		return err
+	}
+	defer removeRecordFile()
+
+	// Run the build with the shims first in PATH
+	buildErr := c.runBuild(args, recordFile)
+
+	records, err := loadRecords(recordFile)
+	if err != nil {
+		return err
+	}
+	if c.Verbose {
+		fmt.Printf("Recorded %d compiler invocations\n", len(records))
+	}
+
+	options, err := PrepareOptions("", c.OutputFile, "", c.BaseDir, c.UseRelativePaths, c.Verbose)
+	if err != nil {
+		return err
+	}
+	options.Compilers = c.Compilers
+	options.OutputFormat = c.OutputFormat
+
+	entries, err := RecordEntries(records, options)
+	if err != nil {
+		return err
+	}
+	if err := GenerateFromEntries(&options, entries); err != nil {
+		return err
+	}
+
+	if buildErr != nil {
+		return errorutil.WrapExecutionError(buildErr, strings.Join(args, " "))
+	}
+	return nil
+}
+
+// createRecordFile creates an empty record file and returns a function removing it
+// when it is temporary
+func (c *InterceptConfig) createRecordFile() (string, func(), error) {
+	if c.RecordFile != "" {
+		path, err := filepath.Abs(c.RecordFile)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return "", nil, errorutil.WrapFileError(err, "create", path)
+		}
+		return path, func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", "yacd-intercept-*.jsonl")
+	if err != nil {
+		return "", nil, errorutil.WrapError(err, "failed to create record file")
+	}
+	file.Close()
+	return file.Name(), func() { os.Remove(file.Name()) }, nil
+}
+
+// runBuild runs the build command with a shim directory for every compiler name
+func (c *InterceptConfig) runBuild(args []string, recordFile string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return errorutil.WrapError(err, "failed to find the yacd executable")
+	}
+
+	names := intercept.CompilerNames(os.Getenv("PATH"), c.Compilers)
+	shimDir, err := intercept.CreateShimDir(executable, names)
+	if err != nil {
+		return errorutil.WrapError(err, "failed to create compiler shims")
+	}
+	defer os.RemoveAll(shimDir)
+
+	if c.Verbose {
+		fmt.Printf("Intercepting %s\n", strings.Join(names, ", "))
+		fmt.Printf("Executing build command: %s\n", strings.Join(args, " "))
+	}
+
+	build := exec.Command(args[0], args[1:]...)
+	// A compiler run directly as the build command goes through its shim as well
+	if shim := filepath.Join(shimDir, args[0]); !strings.ContainsAny(args[0], `/\`) {
+		if _, err := os.Lstat(shim); err == nil {
+			build.Path = shim
+			build.Err = nil
+		}
+	}
+	build.Stdin, build.Stdout, build.Stderr = os.Stdin, os.Stdout, os.Stderr
+	build.Env = intercept.ShimEnvironment(os.Environ(), shimDir, recordFile)
+	return build.Run()
+}
+
+// loadRecords reads the records written by the shims
+func loadRecords(path string) ([]intercept.Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errorutil.WrapFileError(err, "open", path)
+	}
+	defer file.Close()
+
+	records, err := intercept.ReadRecords(file)
+	if err != nil {
+		return nil, errorutil.WrapParseError(err, path)
+	}
+	return records, nil
+}
+
+// RecordEntries converts recorded compiler invocations into log entries, leaving out
+// invocations that compile no source file, such as links and version queries
+func RecordEntries(records []intercept.Record, options types.ParseOptions) ([]types.MakeLogEntry, error) {
+	recordParser, err := parser.NewParser(options)
+	if err != nil {
+		return nil, errorutil.WrapParseError(err, "failed to create parser")
+	}
+
+	var entries []types.MakeLogEntry
+	for _, record := range records {
+		if entry := recordParser.ParseArgs(record.Argv, record.Directory); entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries, nil
+}
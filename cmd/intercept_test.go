@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/intercept"
+	"github.com/gerryqd/yacd/types"
+)
+
+// TestMain lets the test binary act as the compiler shim for the intercept tests
+func TestMain(m *testing.M) {
+	if intercept.IsShim() {
+		os.Exit(intercept.RunShim(os.Args))
+	}
+	os.Exit(m.Run())
+}
+
+func TestRunIntercept(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("intercept is not supported on Windows")
+	}
+
+	// A fake compiler that only creates its output file
+	binDir := t.TempDir()
+	fakeCompiler := "#!/bin/sh\nwhile [ $# -gt 0 ]; do if [ \"$1\" = -o ]; then : > \"$2\"; fi; shift; done\n"
+	if err := os.WriteFile(filepath.Join(binDir, "fakecc"), []byte(fakeCompiler), 0755); err != nil {
+		t.Fatalf("Failed to create fake compiler: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	outputFilePath := filepath.Join(tempDir, "compile_commands.json")
+	recordFilePath := filepath.Join(tempDir, "records.jsonl")
+	script := "cd " + tempDir + "/src && fakecc -DX=1 -c main.c -o main.o && fakecc main.o -o app"
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"intercept", "--compiler", "fakecc", "-o", outputFilePath, "--record", recordFilePath, "--", "sh", "-c", script})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFilePath)
+	if err != nil {
+		t.Fatalf("Output file not created: %v", err)
+	}
+	var entries []types.CompilationEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d: %s", len(entries), data)
+	}
+	if entries[0].Directory != filepath.Join(tempDir, "src") || entries[0].File != filepath.Join(tempDir, "src", "main.c") {
+		t.Errorf("Entry = %s in %s", entries[0].File, entries[0].Directory)
+	}
+	if !strings.HasPrefix(entries[0].Command, filepath.Join(binDir, "fakecc")+" -DX=1") {
+		t.Errorf("Command = %s, expected the real compiler path", entries[0].Command)
+	}
+
+	// Both invocations are recorded, and the real compiler ran
+	records, err := os.ReadFile(recordFilePath)
+	if err != nil || strings.Count(string(records), "\n") != 2 {
+		t.Errorf("Record file = %q, %v, expected 2 records", records, err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "src", "app")); err != nil {
+		t.Errorf("Compiler output missing: %v", err)
+	}
+
+	// A failing build still writes the database and reports the failure
+	testCmd = NewRootCmd()
+	testCmd.SetArgs([]string{"intercept", "-o", outputFilePath, "--", "sh", "-c", "exit 3"})
+	if err := testCmd.Execute(); err == nil {
+		t.Error("Execute() expected error for a failing build")
+	}
+}
+
+// A compiler driver that runs another intercepted compiler is recorded once, since the
+// real compiler runs without the shims in PATH
+func TestRunInterceptNestedCompiler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("intercept is not supported on Windows")
+	}
+
+	binDir := t.TempDir()
+	scripts := map[string]string{
+		"fakecc":     "#!/bin/sh\nexit 0\n",
+		"fakedriver": "#!/bin/sh\nexec fakecc -cc1 \"$@\"\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tempDir := t.TempDir()
+	outputFilePath := filepath.Join(tempDir, "compile_commands.json")
+	recordFilePath := filepath.Join(tempDir, "records.jsonl")
+	script := "cd " + tempDir + " && fakedriver -c main.c -o main.o"
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"intercept", "--compiler", "fakedriver", "--compiler", "fakecc", "-o", outputFilePath, "--record", recordFilePath, "--", "sh", "-c", script})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	records, err := os.ReadFile(recordFilePath)
+	if err != nil {
+		t.Fatalf("Record file not created: %v", err)
+	}
+	if strings.Count(string(records), "\n") != 1 || strings.Contains(string(records), "-cc1") {
+		t.Errorf("Record file = %s, expected only the driver invocation", records)
+	}
+}
@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 
+	"github.com/gerryqd/yacd/parser"
 	"github.com/gerryqd/yacd/types"
 	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
 )
 
 // ExecuteMakeCommand executes a make command with -Bnkw flags
@@ -30,6 +34,123 @@ func ExecuteMakeCommand(makeCmd string) (*exec.Cmd, error) {
 	return cmd, nil
 }
 
+// VerboseBuildVariables make Kbuild, CMake Makefiles and automake silent rules print
+// their compiler commands
+var VerboseBuildVariables = []string{"V=1", "VERBOSE=1", "AM_DEFAULT_VERBOSITY=1"}
+
+// AddVerboseBuildVariables appends the verbose build variables that the make command
+// does not already assign. They are passed as command line variables because Kbuild
+// ignores V from the environment.
+func AddVerboseBuildVariables(makeCmd string) string {
+	parts := strings.Fields(makeCmd)
+	if len(parts) == 0 {
+		return makeCmd
+	}
+
+	for _, variable := range VerboseBuildVariables {
+		name := variable[:strings.Index(variable, "=")+1]
+		assigned := false
+		for _, part := range parts[1:] {
+			if strings.HasPrefix(part, name) {
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			parts = append(parts, variable)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// FindMakeDirectoryArgs returns the directories passed to make via -C/--directory, in order
+func FindMakeDirectoryArgs(makeCmd string) []string {
+	parts := strings.Fields(makeCmd)
+	if len(parts) == 0 {
+		return nil
+	}
+	return parser.FindMakeDirectoryArgs(parts[1:])
+}
+
+// ResolveMakeRootDir returns the absolute directory make will run in.
+// Like make itself, each -C is interpreted relative to the previous one.
+func ResolveMakeRootDir(chdir, makeCmd string) string {
+	rootDir := pathutil.GetWorkingDirectory()
+	if chdir != "" {
+		rootDir = pathutil.ResolveRelativePath(rootDir, chdir)
+	}
+
+	for _, dir := range FindMakeDirectoryArgs(makeCmd) {
+		rootDir = pathutil.ResolveRelativePath(rootDir, dir)
+	}
+
+	return pathutil.NormalizePath(rootDir)
+}
+
+// LoadEnvFile reads KEY=VAL lines from an environment file, skipping blank lines and comments
+func LoadEnvFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errorutil.WrapFileError(err, "open", path)
+	}
+	defer file.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Accept shell-style "export KEY=VAL" lines
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		if err := validateEnvAssignment(line); err != nil {
+			return nil, errorutil.WrapErrorf(err, "%s:%d", path, lineNumber)
+		}
+		env = append(env, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errorutil.WrapFileError(err, "read", path)
+	}
+
+	return env, nil
+}
+
+// BuildMakeEnvironment combines the env file and --env assignments, later entries taking precedence
+func BuildMakeEnvironment(envFile string, env []string) ([]string, error) {
+	var result []string
+
+	if envFile != "" {
+		fileEnv, err := LoadEnvFile(envFile)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fileEnv...)
+	}
+
+	for _, assignment := range env {
+		if err := validateEnvAssignment(assignment); err != nil {
+			return nil, err
+		}
+		result = append(result, assignment)
+	}
+
+	return result, nil
+}
+
+// validateEnvAssignment checks that an assignment has the form KEY=VAL
+func validateEnvAssignment(assignment string) error {
+	key, _, found := strings.Cut(assignment, "=")
+	if !found || strings.TrimSpace(key) == "" {
+		return errorutil.CreateInvalidArgumentError(assignment, "expected KEY=VAL")
+	}
+	return nil
+}
+
 // PrintExecutionInfo prints execution information based on the input source
 func PrintExecutionInfo(options *types.ParseOptions) {
 	fmt.Println("yacd - Yet Another CompileDB")
@@ -39,6 +160,9 @@ func PrintExecutionInfo(options *types.ParseOptions) {
 		fmt.Printf("Input file: %s\n", options.InputFile)
 	} else if options.MakeCommand != "" {
 		fmt.Printf("Make command: %s\n", options.MakeCommand)
+		if options.MakeDirectory != "" {
+			fmt.Printf("Make directory: %s\n", options.MakeDirectory)
+		}
 	} else {
 		fmt.Println("Input source: stdin")
 	}
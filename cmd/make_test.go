@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -193,3 +195,139 @@ func TestPrintExecutionInfoInMake(t *testing.T) {
 		})
 	}
 }
+
+func TestFindMakeDirectoryArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		makeCmd  string
+		expected []string
+	}{
+		{
+			name:     "No directory",
+			makeCmd:  "make all",
+			expected: nil,
+		},
+		{
+			name:     "Separate -C argument",
+			makeCmd:  "make -C firmware all",
+			expected: []string{"firmware"},
+		},
+		{
+			name:     "Attached -C argument",
+			makeCmd:  "make -Cfirmware",
+			expected: []string{"firmware"},
+		},
+		{
+			name:     "Clustered -C argument",
+			makeCmd:  "make -sC firmware -Bk",
+			expected: []string{"firmware"},
+		},
+		{
+			name:     "Long option forms",
+			makeCmd:  "make --directory=/src --directory app",
+			expected: []string{"/src", "app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FindMakeDirectoryArgs(tt.makeCmd)
+			if strings.Join(result, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("FindMakeDirectoryArgs(%q) = %v, expected %v", tt.makeCmd, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddVerboseBuildVariables(t *testing.T) {
+	tests := []struct {
+		makeCmd  string
+		expected string
+	}{
+		{"make all", "make all V=1 VERBOSE=1 AM_DEFAULT_VERBOSITY=1"},
+		{"make V=2 -C fw", "make V=2 -C fw VERBOSE=1 AM_DEFAULT_VERBOSITY=1"},
+		{"make  VERBOSE=1 AM_DEFAULT_VERBOSITY=0", "make VERBOSE=1 AM_DEFAULT_VERBOSITY=0 V=1"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.makeCmd, func(t *testing.T) {
+			if result := AddVerboseBuildVariables(tt.makeCmd); result != tt.expected {
+				t.Errorf("AddVerboseBuildVariables(%q) = %q, expected %q", tt.makeCmd, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveMakeRootDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		chdir    string
+		makeCmd  string
+		expected string
+	}{
+		{
+			name:     "Current directory",
+			makeCmd:  "make",
+			expected: wd,
+		},
+		{
+			name:     "Absolute chdir",
+			chdir:    "/project",
+			makeCmd:  "make",
+			expected: "/project",
+		},
+		{
+			name:     "Chdir combined with -C",
+			chdir:    "/project",
+			makeCmd:  "make -C firmware -C ../bootloader",
+			expected: "/project/bootloader",
+		},
+		{
+			name:     "Relative chdir",
+			chdir:    "build",
+			makeCmd:  "make",
+			expected: filepath.Join(wd, "build"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ResolveMakeRootDir(tt.chdir, tt.makeCmd)
+			if filepath.ToSlash(result) != filepath.ToSlash(tt.expected) {
+				t.Errorf("ResolveMakeRootDir(%q, %q) = %s, expected %s", tt.chdir, tt.makeCmd, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildMakeEnvironment(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "build.env")
+	content := "# toolchain settings\nCROSS_COMPILE=arm-none-eabi-\n\nexport V=1\n"
+	if err := os.WriteFile(envFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create env file: %v", err)
+	}
+
+	env, err := BuildMakeEnvironment(envFile, []string{"V=0", "EMPTY="})
+	if err != nil {
+		t.Fatalf("BuildMakeEnvironment() unexpected error: %v", err)
+	}
+
+	expected := []string{"CROSS_COMPILE=arm-none-eabi-", "V=1", "V=0", "EMPTY="}
+	if strings.Join(env, "|") != strings.Join(expected, "|") {
+		t.Errorf("BuildMakeEnvironment() = %v, expected %v", env, expected)
+	}
+
+	if _, err := BuildMakeEnvironment("", []string{"NOEQUALS"}); err == nil {
+		t.Errorf("BuildMakeEnvironment() expected error for assignment without '='")
+	}
+
+	if _, err := BuildMakeEnvironment(filepath.Join(t.TempDir(), "missing.env"), nil); err == nil {
+		t.Errorf("BuildMakeEnvironment() expected error for missing env file")
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerryqd/yacd/database"
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/parser"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+	"github.com/spf13/cobra"
+)
+
+// MergeConfig holds the options of the merge command
+type MergeConfig struct {
+	*Config
+
+	// Output compile_commands.json file path
+	OutputFile string
+
+	// Output format of the merged database
+	OutputFormat string
+
+	// Policy choosing the entry kept for a file listed more than once
+	Prefer string
+
+	// Options for the -i logs, given with the same flags and configuration file as generate
+	Logs *GenerateConfig
+}
+
+// newMergeCmd creates the merge command
+func newMergeCmd(config *Config) *cobra.Command {
+	mergeConfig := &MergeConfig{Config: config, Logs: &GenerateConfig{Config: config}}
+
+	cmd := &cobra.Command{
+		Use:   "merge [FILE...]",
+		Short: "Merge compilation databases and build logs into one database",
+		Long: `Merge compilation databases, such as those of sub-projects built with CMake or
+Meson, and optionally the entries of freshly parsed build logs, into one database.
+
+Relative directories are resolved against the location of the file they are read
+from. When several entries compile the same file, --prefer decides which is kept.
+Logs given with -i are merged before the database files. They are parsed with the
+options of generate, such as --compiler, --strip-regex, --path-map, --var and the
+flag editing options, and with the configuration file; these options do not apply
+to the database files.
+
+Usage examples:
+  yacd merge build/cmake/compile_commands.json meson/compile_commands.json
+  yacd merge --prefer last old.json new.json -o compile_commands.json
+  yacd merge -i make.log components/*/compile_commands.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return mergeConfig.run(cmd, args)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&mergeConfig.OutputFile, "output", "o", "compile_commands.json", "Output file path (default depends on --output-format)")
+	flags.StringVar(&mergeConfig.OutputFormat, "output-format", generator.DefaultOutputFormat, "Output format: "+strings.Join(generator.OutputFormats(), ", "))
+	flags.StringVar(&mergeConfig.Prefer, "prefer", database.MergeFirst, "Entry kept for a file listed more than once: "+strings.Join(database.MergePolicies(), ", "))
+	addLogFlags(cmd, mergeConfig.Logs)
+	return cmd
+}
+
+// run merges the given databases and logs, keeping one entry for each file
+func (c *MergeConfig) run(cmd *cobra.Command, paths []string) error {
+	if len(paths) == 0 && len(c.Logs.InputFiles) == 0 {
+		return errorutil.NewError("no databases or logs to merge, give database files or -i logs")
+	}
+	writer, err := generator.NewDatabaseWriter(c.OutputFormat)
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("output") {
+		c.OutputFile = writer.DefaultFileName()
+	}
+	if !database.IsValidMergePolicy(c.Prefer) {
+		return errorutil.CreateInvalidArgumentError(c.Prefer, "unsupported merge policy, expected one of: "+strings.Join(database.MergePolicies(), ", "))
+	}
+
+	var dbs []types.CompilationDatabase
+	sources := len(paths)
+	if len(c.Logs.InputFiles) > 0 {
+		db, logCount, err := c.parseLogs(cmd)
+		if err != nil {
+			return err
+		}
+		dbs = append(dbs, db)
+		sources += logCount
+	}
+
+	for _, path := range paths {
+		db, err := database.Load(path)
+		if err != nil {
+			return err
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return errorutil.WrapFileError(err, "resolve", path)
+		}
+		if c.Verbose {
+			fmt.Printf("Loaded %d entries from %s\n", len(db), path)
+		}
+		dbs = append(dbs, database.Rebase(db, filepath.Dir(absPath)))
+	}
+
+	total := 0
+	for _, db := range dbs {
+		total += len(db)
+	}
+	merged := database.MergeWith(c.Prefer, dbs...)
+	if err := generator.WriteCompilationDatabase(merged, c.OutputFile, c.OutputFormat); err != nil {
+		return errorutil.WrapFileError(err, "write compilation database to", c.OutputFile)
+	}
+
+	if duplicates := total - len(merged); duplicates > 0 {
+		fmt.Printf("\033[33mNote: %d duplicate entries resolved with --prefer %s\033[0m\n", duplicates, c.Prefer)
+	}
+	fmt.Printf("\033[32mSuccessfully merged %d files into %s with %d entries\033[0m\n", sources, c.OutputFile, len(merged))
+	return nil
+}
+
+// parseLogs parses the -i logs into a database with absolute paths, also returning the
+// number of logs parsed
+func (c *MergeConfig) parseLogs(cmd *cobra.Command) (types.CompilationDatabase, int, error) {
+	configFile, configPath, err := c.Logs.loadConfigFile()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := c.Logs.applyConfigFile(cmd.Flags(), configFile, false); err != nil {
+		return nil, 0, err
+	}
+	if c.Logs.PathStyle != "" && !pathutil.IsValidPathStyle(c.Logs.PathStyle) {
+		return nil, 0, errorutil.CreateInvalidArgumentError(c.Logs.PathStyle, "unsupported path style, expected one of: "+strings.Join(pathutil.PathStyles(), ", "))
+	}
+	if !parser.IsValidInputFormat(c.Logs.InputFormat) {
+		return nil, 0, errorutil.CreateInvalidArgumentError(c.Logs.InputFormat, "unsupported input format, expected one of: "+strings.Join(parser.InputFormats(), ", "))
+	}
+	if c.Verbose && configPath != "" {
+		fmt.Printf("Using configuration file: %s\n", configPath)
+	}
+
+	inputFiles, err := ExpandInputFiles(c.Logs.InputFiles)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	options, err := PrepareOptions(inputFiles[0], c.OutputFile, "", "", false, c.Verbose)
+	if err != nil {
+		return nil, 0, err
+	}
+	options.InputFiles = inputFiles
+	if err := c.Logs.applyLogOptions(&options); err != nil {
+		return nil, 0, err
+	}
+	options.Variables = BuildVariables(c.Logs.environment(), nil, c.Logs.Variables)
+
+	inputs, cleanup, err := PrepareInputs(options, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cleanup()
+
+	entries, err := ParseInputs(&options, inputs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	db, _, err := generator.GenerateCompilationDatabase(entries, &options)
+	if err != nil {
+		return nil, 0, errorutil.WrapGenerationError(err, "compilation database")
+	}
+	if c.Verbose {
+		fmt.Printf("Parsed %d entries from %s\n", len(db), strings.Join(inputFiles, ", "))
+	}
+	return db, len(inputFiles), nil
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/database"
+)
+
+func TestMergeCmd(t *testing.T) {
+	tempDir := t.TempDir()
+
+	first := filepath.Join(tempDir, "first.json")
+	second := filepath.Join(tempDir, "second.json")
+	output := filepath.Join(tempDir, "merged.json")
+
+	if err := os.WriteFile(first, []byte(`[{"directory": "/project", "command": "gcc -c main.c", "file": "main.c"}]`), 0644); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := os.WriteFile(second, []byte(`[
+  {"directory": "/project", "command": "gcc -O0 -c main.c", "file": "main.c"},
+  {"directory": "/project/lib", "command": "gcc -c list.c", "file": "list.c"}
+]`), 0644); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"merge", first, second, "-o", output})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	merged, err := database.Load(output)
+	if err != nil {
+		t.Fatalf("Failed to load merged database: %v", err)
+	}
+	if len(merged) != 2 {
+		t.Errorf("Merged database has %d entries, expected 2", len(merged))
+	}
+}
+
+func TestMergeCmdRebaseAndLog(t *testing.T) {
+	tempDir := t.TempDir()
+	cmakeDir := filepath.Join(tempDir, "cmake")
+	if err := os.MkdirAll(cmakeDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	// A sub-project database with a directory relative to its own location
+	subDB := filepath.Join(cmakeDir, "compile_commands.json")
+	if err := os.WriteFile(subDB, []byte(`[
+  {"directory": "build", "arguments": ["cc", "-O0", "-c", "../src/engine.c"], "file": "../src/engine.c"},
+  {"directory": "build", "arguments": ["cc", "-c", "../../app/main.c"], "file": "../../app/main.c"}
+]`), 0644); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	// A make log compiling main.c again, merged first so its entry wins
+	logPath := filepath.Join(tempDir, "make.log")
+	log := "make: Entering directory '" + filepath.Join(tempDir, "app") + "'\ngcc -O2 -c main.c -o main.o\n"
+	if err := os.WriteFile(logPath, []byte(log), 0644); err != nil {
+		t.Fatalf("Failed to create test log: %v", err)
+	}
+
+	output := filepath.Join(tempDir, "merged.json")
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"merge", "-i", logPath, subDB, "-o", output})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	merged, err := database.Load(output)
+	if err != nil {
+		t.Fatalf("Failed to load merged database: %v", err)
+	}
+	if len(merged) != 2 {
+		t.Fatalf("Merged database has %d entries, expected 2: %+v", len(merged), merged)
+	}
+	if !strings.Contains(merged[0].Command+strings.Join(merged[0].Arguments, " "), "-O2") {
+		t.Errorf("merged[0] = %+v, expected the make log entry for main.c", merged[0])
+	}
+	if expected := filepath.Join(cmakeDir, "build"); merged[1].Directory != expected {
+		t.Errorf("merged[1].Directory = %s, expected %s", merged[1].Directory, expected)
+	}
+
+	// Unknown policies are rejected
+	testCmd = NewRootCmd()
+	testCmd.SetArgs([]string{"merge", "--prefer", "newest", subDB, "-o", output})
+	if err := testCmd.Execute(); err == nil || !strings.Contains(err.Error(), "unsupported merge policy") {
+		t.Errorf("Execute() error = %v, expected an unsupported merge policy error", err)
+	}
+}
+
+// Logs are parsed with the configuration file and the same options as generate
+func TestMergeCmdLogOptions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	configPath := filepath.Join(tempDir, "yacd.yaml")
+	if err := os.WriteFile(configPath, []byte("compilers: [xcc]\nstrip_regex: ['^\\[[0-9]+/[0-9]+\\] ']\n"), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+	logPath := filepath.Join(tempDir, "build.log")
+	log := "make: Entering directory '/build/app'\n[1/2] xcc -Werror -DNAME=$(NAME) -c main.c -o main.o\n"
+	if err := os.WriteFile(logPath, []byte(log), 0644); err != nil {
+		t.Fatalf("Failed to create test log: %v", err)
+	}
+
+	output := filepath.Join(tempDir, "merged.json")
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"merge", "-i", logPath, "--config", configPath, "--path-map", "/build=" + tempDir,
+		"--remove-flag", "-Werror", "--var", "NAME=demo", "-o", output})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	merged, err := database.Load(output)
+	if err != nil {
+		t.Fatalf("Failed to load merged database: %v", err)
+	}
+	if len(merged) != 1 {
+		t.Fatalf("Merged database has %d entries, expected 1: %+v", len(merged), merged)
+	}
+	if expected := filepath.Join(tempDir, "app"); merged[0].Directory != expected {
+		t.Errorf("Directory = %s, expected %s", merged[0].Directory, expected)
+	}
+	if merged[0].Command != "xcc -DNAME=demo -c "+filepath.Join(tempDir, "app", "main.c")+" -o main.o" {
+		t.Errorf("Command = %s, expected the log command with the flag options applied", merged[0].Command)
+	}
+}
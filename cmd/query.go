@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gerryqd/yacd/database"
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/spf13/cobra"
+)
+
+// Query output formats
+const (
+	queryFormatShell = "shell"
+	queryFormatJSON  = "json"
+	queryFormatFlags = "flags"
+)
+
+// QueryConfig holds the options of the query command
+type QueryConfig struct {
+	*Config
+
+	// Compilation database to search
+	DatabaseFile string
+
+	// Output format: shell, json or flags
+	Format string
+}
+
+// newQueryCmd creates the query command
+func newQueryCmd(config *Config) *cobra.Command {
+	queryConfig := &QueryConfig{Config: config}
+
+	cmd := &cobra.Command{
+		Use:   "query FILE",
+		Short: "Print the compile command for a source file",
+		Long: `Print the compile arguments for a single source file.
+
+Headers without an entry of their own use the arguments of the closest
+translation unit in the same directory tree.
+
+Formats:
+  shell   the command line, quoted for a POSIX shell (default)
+  json    a JSON array of arguments
+  flags   one flag per line in compile_flags.txt form, without the compiler,
+          source file, output file and dependency-generation options`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return queryConfig.run(cmd.OutOrStdout(), args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&queryConfig.DatabaseFile, "database", "d", "compile_commands.json", "Compilation database to search")
+	cmd.Flags().StringVarP(&queryConfig.Format, "format", "f", queryFormatShell, "Output format: shell, json or flags")
+	return cmd
+}
+
+// run prints the arguments of the entry compiling file
+func (c *QueryConfig) run(out io.Writer, file string) error {
+	if c.Format != queryFormatShell && c.Format != queryFormatJSON && c.Format != queryFormatFlags {
+		return errorutil.CreateInvalidArgumentError("--format", fmt.Sprintf("unknown format %q, expected shell, json or flags", c.Format))
+	}
+
+	db, err := database.Load(c.DatabaseFile)
+	if err != nil {
+		return err
+	}
+
+	result, found := database.Query(db, file)
+	if !found {
+		return errorutil.NewErrorf("no entry for %s in %s", file, c.DatabaseFile)
+	}
+
+	if c.Verbose {
+		if result.Fallback {
+			fmt.Fprintf(out, "# flags borrowed from %s in %s\n", result.Entry.File, result.Entry.Directory)
+		} else {
+			fmt.Fprintf(out, "# directory: %s\n", result.Entry.Directory)
+		}
+	}
+
+	switch c.Format {
+	case queryFormatJSON:
+		data, err := json.Marshal(result.Arguments)
+		if err != nil {
+			return errorutil.WrapError(err, "failed to marshal arguments to JSON")
+		}
+		fmt.Fprintln(out, string(data))
+	case queryFormatFlags:
+		flags := generator.CompileFlags(result.Arguments, result.File)
+		if len(flags) > 0 {
+			fmt.Fprintln(out, strings.Join(flags, "\n"))
+		}
+	default:
+		fmt.Fprintln(out, generator.ShellJoin(result.Arguments))
+	}
+
+	return nil
+}
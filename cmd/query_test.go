@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQueryCmd(t *testing.T) {
+	tempDir := t.TempDir()
+	dbFile := filepath.Join(tempDir, "compile_commands.json")
+
+	content := `[{"directory": "/project", "command": "gcc -DMSG=\\\"hi\\ there\\\" -Iinc -c main.c -o main.o", "file": "main.c"}]`
+	if err := os.WriteFile(dbFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "Shell format",
+			args:     []string{"/project/main.c"},
+			expected: "gcc '-DMSG=\"hi there\"' -Iinc -c main.c -o main.o\n",
+		},
+		{
+			name:     "JSON format",
+			args:     []string{"/project/main.c", "--format", "json"},
+			expected: `["gcc","-DMSG=\"hi there\"","-Iinc","-c","main.c","-o","main.o"]` + "\n",
+		},
+		{
+			name:     "Flags format",
+			args:     []string{"/project/main.c", "-f", "flags"},
+			expected: "-DMSG=\"hi there\"\n-Iinc\n",
+		},
+		{
+			name:     "Header fallback",
+			args:     []string{"/project/inc/config.h", "-f", "flags"},
+			expected: "-DMSG=\"hi there\"\n-Iinc\n",
+		},
+		{
+			name:        "Missing file",
+			args:        []string{"/project/missing.c"},
+			expectError: true,
+		},
+		{
+			name:        "Unknown format",
+			args:        []string{"/project/main.c", "-f", "xml"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			testCmd := NewRootCmd()
+			testCmd.SetOut(&out)
+			testCmd.SetErr(&out)
+			testCmd.SetArgs(append([]string{"query", "-d", dbFile}, tt.args...))
+
+			err := testCmd.Execute()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("query expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("query unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("query output = %q, expected %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
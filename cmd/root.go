@@ -3,122 +3,82 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"runtime"
 
+	"github.com/gerryqd/yacd/intercept"
 	"github.com/spf13/cobra"
 )
 
-var (
-	inputFile        string
-	outputFile       string
-	useRelativePaths bool
-	baseDir          string
-	verbose          bool
-	makeCommand      string
-	showVersion      bool
-	GitCommit        string
-)
+// Config holds the options shared by all subcommands
+type Config struct {
+	// Whether to enable verbose output
+	Verbose bool
+}
 
-// Version information
-const (
-	Version = "v0.01"
-)
+// NewRootCmd creates the root command with all subcommands attached.
+// Each call returns an independent command tree with its own configuration.
+func NewRootCmd() *cobra.Command {
+	config := &Config{}
 
-// GetGitCommit retrieves the Git commit hash
-func GetGitCommit() string {
-	// Return the Git commit hash set at build time
-	return GitCommit
-}
+	// Running yacd without a subcommand behaves like "yacd generate"
+	generateConfig := &GenerateConfig{Config: config}
 
-// rootCmd root command
-var rootCmd = &cobra.Command{
-	Use:   "yacd",
-	Short: "Yet Another CompileDB - Generate compile_commands.json from make logs",
-	Long: `yacd (Yet Another CompileDB) is a tool for generating compile_commands.json
+	rootCmd := &cobra.Command{
+		Use:   "yacd",
+		Short: "Yet Another CompileDB - Generate compile_commands.json from make logs",
+		Long: `yacd (Yet Another CompileDB) is a tool for generating compile_commands.json
 files from make logs of makefile projects.
 
 This tool can parse log files generated by 'make -Bnkw' commands, extract
 compilation command information, and generate compile_commands.json files
 that comply with Language Server Protocol standards.
 
+Running yacd without a subcommand is the same as running 'yacd generate'.
+
 Usage examples:
   yacd -i build.log -o compile_commands.json
-  yacd --input make.log --output ./compile_commands.json --verbose
+  yacd generate --input make.log --output ./compile_commands.json --verbose
+  yacd -i 'logs/*.log.gz' -i extra.log.zst -o compile_commands.json
   yacd -i build.log -o compile_commands.json --relative --base-dir /project/root
   yacd -n make --output compile_commands.json --verbose
   yacd --dry-run "make clean all" --output compile_commands.json
+  yacd -n make --chdir firmware --env CROSS_COMPILE=arm-none-eabi-
   yacd < build.log -o compile_commands.json
-  make -Bnkw | yacd -o compile_commands.json`,
-	RunE: runGenerate,
-}
-
-// Execute executes the root command
-func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+  make -Bnkw | yacd -o compile_commands.json
+  yacd check compile_commands.json
+  yacd query src/main.c
+  yacd diff old.json compile_commands.json
+  yacd intercept -- make -j8`,
+		Args: cobra.NoArgs,
+		RunE: generateConfig.run,
 	}
-}
 
-func init() {
-	rootCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input make log file path")
-	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "compile_commands.json", "Output compile_commands.json file path")
-	rootCmd.Flags().BoolVarP(&useRelativePaths, "relative", "r", false, "Use relative paths instead of absolute paths")
-	rootCmd.Flags().StringVarP(&baseDir, "base-dir", "b", "", "Base directory path (used with --relative)")
-	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
-	rootCmd.Flags().StringVarP(&makeCommand, "dry-run", "n", "", "Execute make command with -Bnkw flags and process output directly")
-	rootCmd.Flags().BoolVarP(&showVersion, "version", "V", false, "Print version information and exit")
+	rootCmd.PersistentFlags().BoolVarP(&config.Verbose, "verbose", "v", false, "Verbose output")
+	addGenerateFlags(rootCmd, generateConfig)
 
-	// Mark mutually exclusive parameters
-	rootCmd.MarkFlagsMutuallyExclusive("input", "dry-run")
+	rootCmd.AddCommand(
+		newGenerateCmd(config),
+		newCheckCmd(config),
+		newMergeCmd(config),
+		newQueryCmd(config),
+		newDiffCmd(config),
+		newInterceptCmd(config),
+		newVersionCmd(),
+	)
 
 	// Disable the automatic completion command
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
-}
 
-// runGenerate executes the generation operation
-func runGenerate(cmd *cobra.Command, args []string) error {
-	// Check if version flag is set
-	if showVersion {
-		// Get platform information
-		platform := fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
-		commit := GetGitCommit()
-
-		// Print version information
-		if commit != "unknown" && commit != "" {
-			fmt.Printf("yacd version %s (commit: %s) (%s)\n", Version, commit, platform)
-		} else {
-			fmt.Printf("yacd version %s (%s)\n", Version, platform)
-		}
-		return nil
-	}
-
-	// Check if no input is provided and show help instead of error
-	stdinHasData := HasStdinData()
-	if inputFile == "" && makeCommand == "" && !stdinHasData {
-		// Show help information instead of error when no input is provided
-		cmd.Help()
-		return nil
-	}
-
-	// Validate input sources
-	if err := ValidateInputSources(inputFile, makeCommand, stdinHasData); err != nil {
-		return err
-	}
+	return rootCmd
+}
 
-	// Prepare options
-	options, err := PrepareOptions(inputFile, outputFile, makeCommand, baseDir, useRelativePaths, verbose)
-	if err != nil {
-		return err
+// Execute executes the root command, or runs as a compiler shim under "yacd intercept"
+func Execute() {
+	if intercept.IsShim() {
+		os.Exit(intercept.RunShim(os.Args))
 	}
 
-	// Prepare reader
-	reader, cleanup, err := PrepareReader(options, stdinHasData)
-	if err != nil {
-		return err
+	if err := NewRootCmd().Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
-	defer cleanup()
-
-	// Execute generation
-	return ExecuteGeneration(&options, reader)
 }
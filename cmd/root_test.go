@@ -1,13 +1,14 @@
 package cmd
 
 import (
+	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/gerryqd/yacd/types"
-	"github.com/spf13/cobra"
 )
 
 // Create test make log content
@@ -20,110 +21,50 @@ arm-none-eabi-gcc build/system_mm32f0140.o build/hal_comp.o build/main.o -mcpu=c
 make: Leaving directory '/home/user/project'`
 
 func TestRunGenerateSuccess(t *testing.T) {
-	// Create temporary directory
-	tempDir := t.TempDir()
-
-	// Create test input file
-	inputFilePath := filepath.Join(tempDir, "test.log")
-	outputFilePath := filepath.Join(tempDir, "compile_commands.json")
-
-	if err := os.WriteFile(inputFilePath, []byte(testMakeLog), 0644); err != nil {
-		t.Fatalf("Failed to create test input file: %v", err)
-	}
+	// Running without a subcommand and running "generate" must behave the same
+	for _, subcommand := range [][]string{nil, {"generate"}} {
+		t.Run(strings.Join(append([]string{"yacd"}, subcommand...), " "), func(t *testing.T) {
+			t.Parallel()
 
-	// Create local variables for this test
-	var (
-		testInputFile        string
-		testOutputFile       string
-		testUseRelativePaths bool
-		testBaseDir          string
-		testVerbose          bool
-		testMakeCommand      string
-		testShowVersion      bool
-	)
-
-	// Create a copy of root command for testing
-	testCmd := &cobra.Command{
-		Use: "yacd",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// Use local variables instead of global ones
-			options := types.ParseOptions{
-				InputFile:        testInputFile,
-				OutputFile:       testOutputFile,
-				UseRelativePaths: testUseRelativePaths,
-				BaseDir:          testBaseDir,
-				Verbose:          testVerbose,
-				MakeCommand:      testMakeCommand,
-			}
+			// Create temporary directory
+			tempDir := t.TempDir()
 
-			// Check if version flag is set
-			if testShowVersion {
-				// For testing, we just return nil
-				return nil
-			}
+			// Create test input file
+			inputFilePath := filepath.Join(tempDir, "test.log")
+			outputFilePath := filepath.Join(tempDir, "compile_commands.json")
 
-			// Check if no input is provided and show help instead of error
-			stdinHasData := false // For testing, assume no stdin data
-			if options.InputFile == "" && options.MakeCommand == "" && !stdinHasData {
-				// Show help information instead of error when no input is provided
-				cmd.Help()
-				return nil
+			if err := os.WriteFile(inputFilePath, []byte(testMakeLog), 0644); err != nil {
+				t.Fatalf("Failed to create test input file: %v", err)
 			}
 
-			// Validate input sources
-			if err := ValidateInputSources(options.InputFile, options.MakeCommand, stdinHasData); err != nil {
-				return err
+			// Each command tree has its own configuration
+			testCmd := NewRootCmd()
+			testCmd.SetArgs(append(subcommand,
+				"--input", inputFilePath,
+				"--output", outputFilePath,
+				"--verbose",
+			))
+
+			// Execute command
+			if err := testCmd.Execute(); err != nil {
+				t.Fatalf("Failed to execute command: %v", err)
 			}
 
-			// Prepare options
-			opts, err := PrepareOptions(options.InputFile, options.OutputFile, options.MakeCommand, options.BaseDir, options.UseRelativePaths, options.Verbose)
+			// Check if output file exists and has the three compile entries
+			data, err := os.ReadFile(outputFilePath)
 			if err != nil {
-				return err
+				t.Fatalf("Output file not created: %v", err)
 			}
 
-			// Prepare reader
-			reader, cleanup, err := PrepareReader(opts, stdinHasData)
-			if err != nil {
-				return err
+			var db types.CompilationDatabase
+			if err := json.Unmarshal(data, &db); err != nil {
+				t.Fatalf("Failed to parse output file: %v", err)
 			}
-			defer cleanup()
-
-			// Execute generation
-			return ExecuteGeneration(&opts, reader)
-		},
-	}
-
-	testCmd.Flags().StringVarP(&testInputFile, "input", "i", "", "Input make log file path")
-	testCmd.Flags().StringVarP(&testOutputFile, "output", "o", "compile_commands.json", "Output compile_commands.json file path")
-	testCmd.Flags().BoolVarP(&testUseRelativePaths, "relative", "r", false, "Use relative paths")
-	testCmd.Flags().StringVarP(&testBaseDir, "base-dir", "b", "", "Base directory path")
-	testCmd.Flags().BoolVarP(&testVerbose, "verbose", "v", false, "Verbose output")
-	testCmd.Flags().StringVarP(&testMakeCommand, "dry-run", "n", "", "Execute make command with -Bnkw flags and process output directly")
-	testCmd.Flags().BoolVarP(&testShowVersion, "version", "V", false, "Print version information and exit")
-
-	// Mark mutually exclusive parameters
-	testCmd.MarkFlagsMutuallyExclusive("input", "dry-run")
-
-	// Set parameters
-	testCmd.SetArgs([]string{
-		"--input", inputFilePath,
-		"--output", outputFilePath,
-		"--verbose",
-	})
-
-	// Execute command
-	err := testCmd.Execute()
-	if err != nil {
-		t.Fatalf("Failed to execute command: %v", err)
-	}
-
-	// Check if output file exists
-	if _, err := os.Stat(outputFilePath); os.IsNotExist(err) {
-		t.Fatal("Output file not created")
+			if len(db) != 3 {
+				t.Errorf("Output has %d entries, expected 3", len(db))
+			}
+		})
 	}
-
-	// For now, just check that the file was created successfully
-	// In a real test, we would validate the content as well
 }
 
 func TestValidateInputSourcesInRoot(t *testing.T) {
@@ -281,32 +222,12 @@ func TestHasStdinDataInRoot(t *testing.T) {
 
 func TestRootCmdHelp(t *testing.T) {
 	// Test that the root command provides help when no arguments are given
-	// Create a copy of root command for testing
-	testCmd := &cobra.Command{
-		Use:   "yacd",
-		Short: "Yet Another CompileDB - Generate compile_commands.json from make logs",
-		Long:  `yacd (Yet Another CompileDB) is a tool for generating compile_commands.json files from make logs of makefile projects.`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// Use actual global variables for this test
-			// Check if no input is provided and show help instead of error
-			stdinHasData := HasStdinData()
-			if inputFile == "" && makeCommand == "" && !stdinHasData {
-				// Show help information instead of error when no input is provided
-				cmd.Help()
-				return nil
-			}
-			return nil
-		},
+	if HasStdinData() {
+		t.Skip("stdin has data, root command would read it")
 	}
 
-	// Add flags
-	testCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input make log file path")
-	testCmd.Flags().StringVarP(&outputFile, "output", "o", "compile_commands.json", "Output compile_commands.json file path")
-	testCmd.Flags().BoolVarP(&useRelativePaths, "relative", "r", false, "Use relative paths instead of absolute paths")
-	testCmd.Flags().StringVarP(&baseDir, "base-dir", "b", "", "Base directory path (used with --relative)")
-	testCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
-	testCmd.Flags().StringVarP(&makeCommand, "dry-run", "n", "", "Execute make command with -Bnkw flags and process output directly")
-	testCmd.Flags().BoolVarP(&showVersion, "version", "V", false, "Print version information and exit")
+	testCmd := NewRootCmd()
+	testCmd.SetOut(io.Discard)
 
 	// Execute with no arguments (should show help)
 	testCmd.SetArgs([]string{})
@@ -315,3 +236,215 @@ func TestRootCmdHelp(t *testing.T) {
 		t.Errorf("Root command should not return error when showing help: %v", err)
 	}
 }
+
+func TestRootCmdSubcommands(t *testing.T) {
+	testCmd := NewRootCmd()
+
+	for _, name := range []string{"generate", "check", "merge", "query", "diff", "intercept", "version"} {
+		found, _, err := testCmd.Find([]string{name})
+		if err != nil || found.Name() != name {
+			t.Errorf("Subcommand %s not found", name)
+		}
+	}
+
+	// The -V flag has been replaced by the version subcommand
+	if testCmd.Flags().Lookup("version") != nil {
+		t.Errorf("Root command should not have a --version flag")
+	}
+}
+
+func TestVersionCmd(t *testing.T) {
+	testCmd := NewRootCmd()
+
+	var out strings.Builder
+	testCmd.SetOut(&out)
+	testCmd.SetArgs([]string{"version"})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("version command failed: %v", err)
+	}
+
+	if !strings.HasPrefix(out.String(), "yacd version "+Version) {
+		t.Errorf("version output = %q, expected prefix %q", out.String(), "yacd version "+Version)
+	}
+}
+
+func TestRunGenerateFilters(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expected      int
+		errorContains string
+	}{
+		{"Exclude glob", []string{"--exclude", "Drivers/**"}, 1, ""},
+		{"Include and exclude", []string{"--include", "Drivers/**", "--exclude", "re:hal_"}, 1, ""},
+		{"Directory rule", []string{"--exclude", "dir:/home/user/project"}, 0, ""},
+		{"Invalid regular expression", []string{"--exclude", "re:["}, 0, "re:["},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			inputFilePath := filepath.Join(tempDir, "test.log")
+			outputFilePath := filepath.Join(tempDir, "compile_commands.json")
+			if err := os.WriteFile(inputFilePath, []byte(testMakeLog), 0644); err != nil {
+				t.Fatalf("Failed to create test input file: %v", err)
+			}
+
+			testCmd := NewRootCmd()
+			testCmd.SetArgs(append([]string{"--input", inputFilePath, "--output", outputFilePath, "--no-config"}, tt.args...))
+
+			err := testCmd.Execute()
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Execute() error = %v, expected error containing %q", err, tt.errorContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to execute command: %v", err)
+			}
+
+			data, err := os.ReadFile(outputFilePath)
+			if err != nil {
+				t.Fatalf("Output file not created: %v", err)
+			}
+			var db types.CompilationDatabase
+			if err := json.Unmarshal(data, &db); err != nil {
+				t.Fatalf("Failed to parse output file: %v", err)
+			}
+			if len(db) != tt.expected {
+				t.Errorf("Output has %d entries, expected %d", len(db), tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunGenerateOutputFormat(t *testing.T) {
+	tempDir := t.TempDir()
+	inputFilePath := filepath.Join(tempDir, "test.log")
+	if err := os.WriteFile(inputFilePath, []byte(testMakeLog), 0644); err != nil {
+		t.Fatalf("Failed to create test input file: %v", err)
+	}
+
+	// Without --output the file name follows the format
+	originalDir, _ := os.Getwd()
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"--input", inputFilePath, "--output-format", "compile-flags", "--no-config"})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "compile_flags.txt"))
+	if err != nil {
+		t.Fatalf("Output file not created: %v", err)
+	}
+	if !strings.Contains(string(data), "-DNDEBUG\n") || !strings.Contains(string(data), "-I/home/user/project/Core/Inc\n") {
+		t.Errorf("compile_flags.txt does not contain the shared flags:\n%s", data)
+	}
+
+	testCmd = NewRootCmd()
+	testCmd.SetArgs([]string{"--input", inputFilePath, "--output-format", "xml", "--no-config"})
+	if err := testCmd.Execute(); err == nil || !strings.Contains(err.Error(), "xml") {
+		t.Errorf("Execute() error = %v, expected unsupported format error", err)
+	}
+}
+
+func TestRunGenerateKbuildDir(t *testing.T) {
+	tempDir := t.TempDir()
+	cmdFile := filepath.Join(tempDir, "drivers", ".uart.o.cmd")
+	if err := os.MkdirAll(filepath.Dir(cmdFile), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	content := "savedcmd_drivers/uart.o := gcc -Iinclude -c -o drivers/uart.o drivers/uart.c\n"
+	if err := os.WriteFile(cmdFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create command file: %v", err)
+	}
+
+	outputFilePath := filepath.Join(tempDir, "compile_commands.json")
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"--kbuild-dir", tempDir, "--output", outputFilePath, "--no-config"})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFilePath)
+	if err != nil {
+		t.Fatalf("Output file not created: %v", err)
+	}
+	var entries []types.CompilationEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Directory != tempDir || entries[0].File != filepath.Join(tempDir, "drivers", "uart.c") {
+		t.Errorf("Entry = %s in %s, expected drivers/uart.c in %s", entries[0].File, entries[0].Directory, tempDir)
+	}
+}
+
+func TestRunGenerateNinjaLog(t *testing.T) {
+	buildDir := t.TempDir()
+	manifest := "rule cc\n  command = gcc $cflags -c $in -o $out\nbuild obj/app.o: cc ../src/app.c\n  cflags = -Iinclude\n"
+	if err := os.WriteFile(filepath.Join(buildDir, "build.ninja"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to create build.ninja: %v", err)
+	}
+	logPath := filepath.Join(buildDir, ".ninja_log")
+	if err := os.WriteFile(logPath, []byte("# ninja log v5\n0\t10\t100\tobj/app.o\t1a\n"), 0644); err != nil {
+		t.Fatalf("Failed to create .ninja_log: %v", err)
+	}
+
+	outputFilePath := filepath.Join(t.TempDir(), "compile_commands.json")
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"--input", logPath, "--output", outputFilePath, "--no-config"})
+	if err := testCmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFilePath)
+	if err != nil {
+		t.Fatalf("Output file not created: %v", err)
+	}
+	var entries []types.CompilationEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	expectedFile := filepath.Join(filepath.Dir(buildDir), "src", "app.c")
+	if entries[0].Directory != buildDir || entries[0].File != expectedFile {
+		t.Errorf("Entry = %s in %s, expected %s in %s", entries[0].File, entries[0].Directory, expectedFile, buildDir)
+	}
+
+	// Unknown formats are rejected
+	testCmd = NewRootCmd()
+	testCmd.SetArgs([]string{"--input", logPath, "--input-format", "bogus", "--output", outputFilePath, "--no-config"})
+	if err := testCmd.Execute(); err == nil || !strings.Contains(err.Error(), "unsupported input format") {
+		t.Errorf("Execute() error = %v, expected an unsupported input format error", err)
+	}
+}
+
+func TestRunGenerateQuietBuild(t *testing.T) {
+	tempDir := t.TempDir()
+	inputFilePath := filepath.Join(tempDir, "quiet.log")
+	quietLog := "make[1]: Entering directory '/linux'\n  CC      init/main.o\n  CC      init/version.o\n"
+	if err := os.WriteFile(inputFilePath, []byte(quietLog), 0644); err != nil {
+		t.Fatalf("Failed to create test input file: %v", err)
+	}
+
+	testCmd := NewRootCmd()
+	testCmd.SetArgs([]string{"--input", inputFilePath, "--output", filepath.Join(tempDir, "out.json"), "--no-config"})
+	err := testCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "V=1") || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Execute() error = %v, expected a quiet build diagnostic", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(tempDir, "out.json")); statErr == nil {
+		t.Error("Output file should not be written for a quiet build log")
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// GitCommit is set at build time via -ldflags
+var GitCommit string
+
+// Version information
+const (
+	Version = "v0.01"
+)
+
+// GetGitCommit retrieves the Git commit hash
+func GetGitCommit() string {
+	// Return the Git commit hash set at build time
+	return GitCommit
+}
+
+// newVersionCmd creates the version command
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print version information",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprintln(cmd.OutOrStdout(), VersionString())
+		},
+	}
+}
+
+// VersionString returns the version line printed by the version command
+func VersionString() string {
+	// Get platform information
+	platform := fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
+	commit := GetGitCommit()
+
+	if commit != "unknown" && commit != "" {
+		return fmt.Sprintf("yacd version %s (commit: %s) (%s)", Version, commit, platform)
+	}
+	return fmt.Sprintf("yacd version %s (%s)", Version, platform)
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the configuration file names looked up in each directory, in order
+var FileNames = []string{".yacd.yaml", ".yacd.yml", ".yacd.toml"}
+
+// File holds per-project defaults read from a .yacd.yaml or .yacd.toml file
+type File struct {
+	// Format of the input logs: auto, make, ninja, ninja-log or aquery
+	InputFormat string `yaml:"input_format,omitempty" toml:"input_format,omitempty"`
+
+	// Output compile_commands.json file path
+	Output string `yaml:"output,omitempty" toml:"output,omitempty"`
+
+	// Output format of the compilation database
+	OutputFormat string `yaml:"output_format,omitempty" toml:"output_format,omitempty"`
+
+	// Whether to use relative paths
+	Relative bool `yaml:"relative,omitempty" toml:"relative,omitempty"`
+
+	// Base directory for relative paths
+	BaseDir string `yaml:"base_dir,omitempty" toml:"base_dir,omitempty"`
+
+	// Working directory for entries without directory information
+	Directory string `yaml:"directory,omitempty" toml:"directory,omitempty"`
+
+	// Whether to infer working directories when make does not print them
+	InferDirectories bool `yaml:"infer_directories,omitempty" toml:"infer_directories,omitempty"`
+
+	// Style Windows drive paths are written in: posix, msys, cygwin or windows
+	PathStyle string `yaml:"path_style,omitempty" toml:"path_style,omitempty"`
+
+	// Whether to resolve symbolic links in directories and files
+	ResolveSymlinks bool `yaml:"resolve_symlinks,omitempty" toml:"resolve_symlinks,omitempty"`
+
+	// Whether to add entries for headers without their own entry
+	InferHeaders bool `yaml:"infer_headers,omitempty" toml:"infer_headers,omitempty"`
+
+	// Whether to find headers by following #include directives
+	HeaderIncludeScan bool `yaml:"header_include_scan,omitempty" toml:"header_include_scan,omitempty"`
+
+	// Additional regular expressions stripped from every log line
+	StripRegex []string `yaml:"strip_regex,omitempty" toml:"strip_regex,omitempty"`
+
+	// Additional compiler executable names to recognise
+	Compilers []string `yaml:"compilers,omitempty" toml:"compilers,omitempty"`
+
+	// Launcher executables skipped before the compiler
+	Wrappers []string `yaml:"wrappers,omitempty" toml:"wrappers,omitempty"`
+
+	// Path prefix rewrites
+	PathMaps []types.PathMap `yaml:"path_maps,omitempty" toml:"path_maps,omitempty"`
+
+	// Values of variables referenced in logs
+	Variables map[string]string `yaml:"variables,omitempty" toml:"variables,omitempty"`
+
+	// Whether variables referenced in logs are also looked up in the environment
+	VariablesFromEnv bool `yaml:"variables_from_env,omitempty" toml:"variables_from_env,omitempty"`
+
+	// Rules keeping only matching entries
+	Include []string `yaml:"include,omitempty" toml:"include,omitempty"`
+
+	// Rules removing matching entries
+	Exclude []string `yaml:"exclude,omitempty" toml:"exclude,omitempty"`
+
+	// Glob patterns of compiler flags removed from every entry
+	RemoveFlags []string `yaml:"remove_flags,omitempty" toml:"remove_flags,omitempty"`
+
+	// Compiler argument edits, optionally scoped by file or compiler
+	FlagRules []types.FlagRule `yaml:"flag_rules,omitempty" toml:"flag_rules,omitempty"`
+
+	// Make command executed in dry-run mode
+	MakeCommand string `yaml:"make_command,omitempty" toml:"make_command,omitempty"`
+
+	// Directory to run the make command in
+	MakeDirectory string `yaml:"make_directory,omitempty" toml:"make_directory,omitempty"`
+
+	// KEY=VAL assignments for the make command environment
+	Env []string `yaml:"env,omitempty" toml:"env,omitempty"`
+
+	// File with KEY=VAL lines for the make command environment
+	EnvFile string `yaml:"env_file,omitempty" toml:"env_file,omitempty"`
+
+	// Whether to ask quiet build systems for their commands in dry-run mode
+	VerboseBuild bool `yaml:"verbose_build,omitempty" toml:"verbose_build,omitempty"`
+}
+
+// Find walks up from dir and returns the path of the first configuration file found,
+// or an empty string when there is none
+func Find(dir string) string {
+	dir = pathutil.NormalizePath(dir)
+
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads a YAML or TOML configuration file. Unknown keys are rejected and
+// relative paths are resolved against the directory containing the file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errorutil.WrapFileError(err, "read", path)
+	}
+
+	file := &File{}
+	if strings.HasSuffix(path, ".toml") {
+		metadata, err := toml.Decode(string(data), file)
+		if err != nil {
+			return nil, errorutil.WrapParseError(err, path)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			sort.Strings(keys)
+			return nil, errorutil.NewErrorf("unknown configuration keys in %s: %s", path, strings.Join(keys, ", "))
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, errorutil.WrapParseError(err, path)
+		}
+	}
+
+	file.resolvePaths(filepath.Dir(path))
+	return file, nil
+}
+
+// resolvePaths makes the path settings absolute relative to dir
+func (f *File) resolvePaths(dir string) {
+	for _, path := range []*string{&f.Output, &f.BaseDir, &f.Directory, &f.MakeDirectory, &f.EnvFile} {
+		if *path != "" {
+			*path = pathutil.NormalizePath(pathutil.ResolveRelativePath(dir, *path))
+		}
+	}
+}
+
+// Marshal renders the configuration as YAML
+func (f *File) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return nil, errorutil.WrapError(err, "failed to marshal configuration")
+	}
+	return data, nil
+}
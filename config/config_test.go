@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	if result := Find(nested); result != "" && strings.HasPrefix(result, root) {
+		t.Errorf("Find() = %q, expected no configuration below %s", result, root)
+	}
+
+	tomlPath := filepath.Join(root, ".yacd.toml")
+	if err := os.WriteFile(tomlPath, nil, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if result := Find(nested); result != tomlPath {
+		t.Errorf("Find() = %q, expected %q", result, tomlPath)
+	}
+
+	// YAML is preferred over TOML in the same directory, nearer files over farther ones
+	yamlPath := filepath.Join(root, "a", ".yacd.yaml")
+	if err := os.WriteFile(yamlPath, nil, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", ".yacd.toml"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if result := Find(nested); result != yamlPath {
+		t.Errorf("Find() = %q, expected %q", result, yamlPath)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		content     string
+		expectError string
+	}{
+		{
+			name:     "YAML",
+			fileName: ".yacd.yaml",
+			content: `relative: true
+base_dir: .
+compilers: [xc8-cc]
+path_maps:
+  - from: /build
+    to: /home/dev/src
+remove_flags: ["-Werror"]
+make_command: make all
+`,
+		},
+		{
+			name:     "TOML",
+			fileName: ".yacd.toml",
+			content: `relative = true
+base_dir = "."
+compilers = ["xc8-cc"]
+remove_flags = ["-Werror"]
+make_command = "make all"
+
+[[path_maps]]
+from = "/build"
+to = "/home/dev/src"
+`,
+		},
+		{
+			name:     "Empty YAML",
+			fileName: ".yacd.yaml",
+			content:  "",
+		},
+		{
+			name:        "Unknown YAML key",
+			fileName:    ".yacd.yaml",
+			content:     "relativ: true\n",
+			expectError: "relativ",
+		},
+		{
+			name:        "Unknown TOML key",
+			fileName:    ".yacd.toml",
+			content:     "relativ = true\n",
+			expectError: "unknown configuration keys",
+		},
+		{
+			name:        "Invalid YAML",
+			fileName:    ".yacd.yaml",
+			content:     "compilers: [gcc\n",
+			expectError: "failed to parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tt.fileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			file, err := Load(path)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("Load() error = %v, expected error containing %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if tt.content == "" {
+				return
+			}
+
+			if !file.Relative || file.MakeCommand != "make all" {
+				t.Errorf("Load() = %+v, expected relative and make command", file)
+			}
+			if file.BaseDir != dir {
+				t.Errorf("Load() base_dir = %q, expected it resolved to %q", file.BaseDir, dir)
+			}
+			if len(file.Compilers) != 1 || file.Compilers[0] != "xc8-cc" || len(file.RemoveFlags) != 1 {
+				t.Errorf("Load() lists = %v %v", file.Compilers, file.RemoveFlags)
+			}
+			if len(file.PathMaps) != 1 || file.PathMaps[0].From != "/build" || file.PathMaps[0].To != "/home/dev/src" {
+				t.Errorf("Load() path_maps = %v", file.PathMaps)
+			}
+		})
+	}
+}
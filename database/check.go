@@ -0,0 +1,94 @@
+package database
+
+import (
+	"os"
+
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/diagutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// CheckOptions controls database validation
+type CheckOptions struct {
+	// Directory containing the database, used to resolve relative directories
+	DatabaseDir string
+
+	// Whether to check that include directories exist
+	CheckIncludeDirs bool
+}
+
+// Check validates a compilation database against the JSON compilation database
+// specification and the filesystem
+func Check(db types.CompilationDatabase, options CheckOptions) []diagutil.Diagnostic {
+	var diagnostics []diagutil.Diagnostic
+	firstEntryForFile := make(map[string]int)
+
+	for i, entry := range db {
+		index := i + 1
+
+		// Required fields
+		if entry.Directory == "" {
+			diagnostics = append(diagnostics, diagutil.Errorf(index, "missing required field \"directory\""))
+		}
+		if entry.File == "" {
+			diagnostics = append(diagnostics, diagutil.Errorf(index, "missing required field \"file\""))
+		}
+		if entry.Command == "" && len(entry.Arguments) == 0 {
+			diagnostics = append(diagnostics, diagutil.Errorf(index, "one of \"command\" or \"arguments\" is required"))
+		}
+		if entry.Command != "" && len(entry.Arguments) > 0 {
+			diagnostics = append(diagnostics, diagutil.Errorf(index, "\"command\" and \"arguments\" are mutually exclusive"))
+		}
+		if entry.Directory == "" || entry.File == "" {
+			continue
+		}
+
+		// Directory must be absolute, or at least resolvable from the database location
+		directory := entry.Directory
+		if !pathutil.IsAbsolutePath(directory) {
+			directory = pathutil.JoinPaths(options.DatabaseDir, directory)
+			if isDir(directory) {
+				diagnostics = append(diagnostics, diagutil.Warningf(index, "directory is not absolute: %s", entry.Directory))
+			} else {
+				diagnostics = append(diagnostics, diagutil.Errorf(index, "directory is not absolute and cannot be resolved: %s", entry.Directory))
+				continue
+			}
+		} else if !isDir(directory) {
+			diagnostics = append(diagnostics, diagutil.Errorf(index, "directory does not exist: %s", entry.Directory))
+		}
+
+		// Source file must exist
+		file := pathutil.JoinPaths(directory, entry.File)
+		if pathutil.IsAbsolutePath(entry.File) {
+			file = pathutil.NormalizePath(entry.File)
+		}
+		if _, err := os.Stat(file); err != nil {
+			diagnostics = append(diagnostics, diagutil.Errorf(index, "source file does not exist: %s", entry.File))
+		}
+
+		// Each file should have a single entry
+		if first, ok := firstEntryForFile[file]; ok {
+			diagnostics = append(diagnostics, diagutil.Errorf(index, "duplicate entry for %s (first defined in entry %d)", entry.File, first))
+		} else {
+			firstEntryForFile[file] = index
+		}
+
+		// Include directories should exist
+		if options.CheckIncludeDirs {
+			for _, includeDir := range generator.IncludeDirectories(generator.EntryArguments(entry)) {
+				if !isDir(pathutil.ResolveRelativePath(directory, includeDir)) {
+					diagnostics = append(diagnostics, diagutil.Warningf(index, "include directory does not exist: %s", includeDir))
+				}
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+// isDir reports whether path exists and is a directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/diagutil"
+)
+
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"src", "include"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	for _, file := range []string{"src/main.c", "src/util.c"} {
+		if err := os.WriteFile(filepath.Join(root, file), []byte("int x;\n"), 0644); err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		entry    types.CompilationEntry
+		expected []string // Expected diagnostics as "Severity: message" prefixes
+	}{
+		{
+			name: "Valid entry",
+			entry: types.CompilationEntry{
+				Directory: root,
+				Arguments: []string{"gcc", "-Iinclude", "-c", "src/main.c"},
+				File:      "src/main.c",
+			},
+			expected: nil,
+		},
+		{
+			name:     "Missing required fields",
+			entry:    types.CompilationEntry{},
+			expected: []string{"Error: missing required field \"directory\"", "Error: missing required field \"file\"", "Error: one of \"command\""},
+		},
+		{
+			name: "Command and arguments both set",
+			entry: types.CompilationEntry{
+				Directory: root,
+				Command:   "gcc -c src/util.c",
+				Arguments: []string{"gcc", "-c", "src/util.c"},
+				File:      "src/util.c",
+			},
+			expected: []string{"Error: \"command\" and \"arguments\" are mutually exclusive"},
+		},
+		{
+			name: "Relative directory resolvable from database location",
+			entry: types.CompilationEntry{
+				Directory: "src",
+				Command:   "gcc -c util.c",
+				File:      "util.c",
+			},
+			expected: []string{"Warning: directory is not absolute"},
+		},
+		{
+			name: "Unresolvable directory",
+			entry: types.CompilationEntry{
+				Directory: "nowhere",
+				Command:   "gcc -c util.c",
+				File:      "util.c",
+			},
+			expected: []string{"Error: directory is not absolute and cannot be resolved"},
+		},
+		{
+			name: "Missing source and include directory",
+			entry: types.CompilationEntry{
+				Directory: root,
+				Command:   "gcc -I missing -isystem/usr/include/none -c src/gone.c",
+				File:      "src/gone.c",
+			},
+			expected: []string{
+				"Error: source file does not exist",
+				"Warning: include directory does not exist: missing",
+				"Warning: include directory does not exist: /usr/include/none",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := Check(types.CompilationDatabase{tt.entry}, CheckOptions{DatabaseDir: root, CheckIncludeDirs: true})
+			if len(diagnostics) != len(tt.expected) {
+				t.Fatalf("Check() returned %d diagnostics %v, expected %d", len(diagnostics), diagnostics, len(tt.expected))
+			}
+			for i, expected := range tt.expected {
+				if !strings.HasPrefix(diagnostics[i].String(), expected) {
+					t.Errorf("Check() diagnostic %d = %q, expected prefix %q", i, diagnostics[i].String(), expected)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDuplicates(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.c"), []byte("int x;\n"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	db := types.CompilationDatabase{
+		{Directory: root, Command: "gcc -c main.c", File: "main.c"},
+		{Directory: root, Command: "gcc -O2 -c main.c", File: filepath.Join(root, "main.c")},
+	}
+
+	diagnostics := Check(db, CheckOptions{})
+	if diagutil.CountErrors(diagnostics) != 1 {
+		t.Fatalf("Check() diagnostics = %v, expected one duplicate error", diagnostics)
+	}
+	if diagnostics[0].Entry != 2 || !strings.Contains(diagnostics[0].Message, "first defined in entry 1") {
+		t.Errorf("Check() diagnostic = %v, expected duplicate of entry 1 reported on entry 2", diagnostics[0])
+	}
+}
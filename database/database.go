@@ -0,0 +1,116 @@
+package database
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// Load reads a compile_commands.json file into a compilation database
+func Load(path string) (types.CompilationDatabase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errorutil.WrapFileError(err, "read", path)
+	}
+
+	var db types.CompilationDatabase
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, errorutil.WrapParseError(err, path)
+	}
+
+	return db, nil
+}
+
+// FindEntry returns the entry compiling the given file. Relative paths are resolved
+// against the current working directory.
+func FindEntry(db types.CompilationDatabase, file string) (types.CompilationEntry, bool) {
+	target := pathutil.NormalizePath(pathutil.ResolveRelativePath(pathutil.GetWorkingDirectory(), file))
+
+	for _, entry := range db {
+		if generator.EntryFilePath(entry) == target {
+			return entry, true
+		}
+	}
+
+	return types.CompilationEntry{}, false
+}
+
+// Merge policies deciding which entry is kept when several compile the same file
+const (
+	// MergeFirst keeps the entry from the first database listing the file
+	MergeFirst = "first"
+
+	// MergeLast keeps the entry from the last database listing the file
+	MergeLast = "last"
+
+	// MergeLongest keeps the entry with the most arguments, the first one on a tie
+	MergeLongest = "longest"
+)
+
+// MergePolicies returns the names of the supported merge policies
+func MergePolicies() []string {
+	return []string{MergeFirst, MergeLast, MergeLongest}
+}
+
+// IsValidMergePolicy reports whether policy names a supported merge policy
+func IsValidMergePolicy(policy string) bool {
+	for _, name := range MergePolicies() {
+		if policy == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Rebase resolves relative entry directories against dir, the directory of the
+// database file they were read from
+func Rebase(db types.CompilationDatabase, dir string) types.CompilationDatabase {
+	rebased := make(types.CompilationDatabase, len(db))
+	for i, entry := range db {
+		if !pathutil.IsAbsolutePath(entry.Directory) {
+			entry.Directory = pathutil.NormalizePath(filepath.Join(dir, entry.Directory))
+		}
+		rebased[i] = entry
+	}
+	return rebased
+}
+
+// Merge combines databases, keeping the first entry for each source file
+func Merge(dbs ...types.CompilationDatabase) types.CompilationDatabase {
+	return MergeWith(MergeFirst, dbs...)
+}
+
+// MergeWith combines databases, keeping one entry for each source file chosen by the
+// merge policy. Files stay in the order they are first seen.
+func MergeWith(policy string, dbs ...types.CompilationDatabase) types.CompilationDatabase {
+	merged := types.CompilationDatabase{}
+	index := make(map[string]int)
+
+	for _, db := range dbs {
+		for _, entry := range db {
+			key := generator.EntryFilePath(entry)
+			i, seen := index[key]
+			if !seen {
+				index[key] = len(merged)
+				merged = append(merged, entry)
+				continue
+			}
+
+			switch policy {
+			case MergeLast:
+				merged[i] = entry
+			case MergeLongest:
+				if len(generator.EntryArguments(entry)) > len(generator.EntryArguments(merged[i])) {
+					merged[i] = entry
+				}
+			}
+		}
+	}
+
+	return merged
+}
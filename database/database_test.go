@@ -0,0 +1,152 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestLoad(t *testing.T) {
+	tempDir := t.TempDir()
+
+	validFile := filepath.Join(tempDir, "compile_commands.json")
+	content := `[
+  {"directory": "/project", "command": "gcc -c main.c", "file": "main.c"},
+  {"directory": "/project", "arguments": ["gcc", "-c", "util.c"], "file": "util.c", "output": "util.o"}
+]`
+	if err := os.WriteFile(validFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	db, err := Load(validFile)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(db) != 2 {
+		t.Fatalf("Load() returned %d entries, expected 2", len(db))
+	}
+	if len(db[1].Arguments) != 3 || db[1].Output != "util.o" {
+		t.Errorf("Load() second entry = %+v", db[1])
+	}
+
+	invalidFile := filepath.Join(tempDir, "invalid.json")
+	if err := os.WriteFile(invalidFile, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if _, err := Load(invalidFile); err == nil {
+		t.Errorf("Load() expected error for invalid JSON")
+	}
+
+	if _, err := Load(filepath.Join(tempDir, "missing.json")); err == nil {
+		t.Errorf("Load() expected error for missing file")
+	}
+}
+
+func TestFindEntry(t *testing.T) {
+	db := types.CompilationDatabase{
+		{Directory: "/project", Command: "gcc -c src/main.c", File: "src/main.c"},
+		{Directory: "/project/lib", Command: "gcc -c /project/lib/list.c", File: "/project/lib/list.c"},
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+		found    bool
+	}{
+		{name: "Relative file in entry", file: "/project/src/main.c", expected: "gcc -c src/main.c", found: true},
+		{name: "Unclean query path", file: "/project/lib/../lib//list.c", expected: "gcc -c /project/lib/list.c", found: true},
+		{name: "Missing file", file: "/project/src/other.c", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, found := FindEntry(db, tt.file)
+			if found != tt.found {
+				t.Fatalf("FindEntry(%s) found = %v, expected %v", tt.file, found, tt.found)
+			}
+			if found && entry.Command != tt.expected {
+				t.Errorf("FindEntry(%s) command = %s, expected %s", tt.file, entry.Command, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first := types.CompilationDatabase{
+		{Directory: "/project", Command: "gcc -O2 -c main.c", File: "main.c"},
+	}
+	second := types.CompilationDatabase{
+		{Directory: "/project", Command: "gcc -O0 -c /project/main.c", File: "/project/main.c"},
+		{Directory: "/project", Command: "gcc -c util.c", File: "util.c"},
+	}
+
+	merged := Merge(first, second)
+	if len(merged) != 2 {
+		t.Fatalf("Merge() returned %d entries, expected 2", len(merged))
+	}
+	if merged[0].Command != "gcc -O2 -c main.c" {
+		t.Errorf("Merge() should keep the first entry for a file, got %s", merged[0].Command)
+	}
+}
+
+func TestMergeWith(t *testing.T) {
+	first := types.CompilationDatabase{
+		{Directory: "/project", Command: "gcc -c main.c", File: "main.c"},
+		{Directory: "/project", Command: "gcc -c util.c", File: "util.c"},
+	}
+	second := types.CompilationDatabase{
+		{Directory: "/project", Arguments: []string{"gcc", "-O2", "-DNDEBUG", "-c", "main.c"}, File: "/project/main.c"},
+		{Directory: "/project", Command: "gcc -c extra.c", File: "extra.c"},
+	}
+	third := types.CompilationDatabase{
+		{Directory: "/project/build/..", Command: "gcc -O0 -c main.c", File: "main.c"},
+	}
+
+	tests := []struct {
+		policy   string
+		expected []string
+	}{
+		{MergeFirst, []string{"gcc -c main.c", "gcc -c util.c", "gcc -c extra.c"}},
+		{MergeLast, []string{"gcc -O0 -c main.c", "gcc -c util.c", "gcc -c extra.c"}},
+		{MergeLongest, []string{"gcc -O2 -DNDEBUG -c main.c", "gcc -c util.c", "gcc -c extra.c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			merged := MergeWith(tt.policy, first, second, third)
+			if len(merged) != len(tt.expected) {
+				t.Fatalf("MergeWith() returned %d entries, expected %d", len(merged), len(tt.expected))
+			}
+			for i, expected := range tt.expected {
+				if command := strings.Join(generator.EntryArguments(merged[i]), " "); command != expected {
+					t.Errorf("merged[%d] = %s, expected %s", i, command, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRebase(t *testing.T) {
+	db := types.CompilationDatabase{
+		{Directory: "/project/lib", Command: "gcc -c a.c", File: "a.c"},
+		{Directory: "build", Command: "gcc -c ../b.c", File: "../b.c"},
+		{Directory: ".", Command: "gcc -c c.c", File: "c.c"},
+		{Directory: "", Command: "gcc -c d.c", File: "d.c"},
+	}
+
+	rebased := Rebase(db, "/project/sub")
+	expected := []string{"/project/lib", "/project/sub/build", "/project/sub", "/project/sub"}
+	for i, dir := range expected {
+		if rebased[i].Directory != dir {
+			t.Errorf("rebased[%d].Directory = %s, expected %s", i, rebased[i].Directory, dir)
+		}
+	}
+	if db[1].Directory != "build" {
+		t.Errorf("Rebase() modified its input: %s", db[1].Directory)
+	}
+}
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/types"
+)
+
+// Options whose value names an include directory; their order decides header lookup
+var includeOrderOptions = []string{"-isystem", "-iquote", "-idirafter", "-I"}
+
+// DatabaseDiff is the difference between two compilation databases
+type DatabaseDiff struct {
+	// Files only in the new database
+	Added []string `json:"added"`
+
+	// Files only in the old database
+	Removed []string `json:"removed"`
+
+	// Files in both databases compiled differently
+	Changed []FileDiff `json:"changed"`
+}
+
+// FileDiff describes how the compilation of one file changed
+type FileDiff struct {
+	// Absolute path of the source file
+	File string `json:"file"`
+
+	// Changed working directory
+	Directory *ValueChange `json:"directory,omitempty"`
+
+	// Changed compiler executable
+	Compiler *ValueChange `json:"compiler,omitempty"`
+
+	// Flags only in the new command
+	AddedFlags []string `json:"added_flags,omitempty"`
+
+	// Flags only in the old command
+	RemovedFlags []string `json:"removed_flags,omitempty"`
+
+	// Macros defined in both commands with different values
+	Defines []DefineChange `json:"defines,omitempty"`
+
+	// Include directories kept in both commands but searched in a different order
+	IncludeOrder *ListChange `json:"include_order,omitempty"`
+
+	// Other flags kept in both commands but given in a different order
+	Reordered *ListChange `json:"reordered,omitempty"`
+}
+
+// ValueChange is a value before and after a change
+type ValueChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// DefineChange is a macro whose value changed; a macro defined without a value has an
+// empty value
+type DefineChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// ListChange is an ordered list before and after a change
+type ListChange struct {
+	Old []string `json:"old"`
+	New []string `json:"new"`
+}
+
+// Empty reports whether the databases compile the same files the same way
+func (d DatabaseDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares two databases by source file. Arguments are compared in their split
+// form, so "command" and "arguments" entries compare equal, and options given with a
+// separate value (-I inc) compare equal to the attached form (-Iinc). Output files and
+// dependency-generation options are ignored.
+func Diff(oldDB, newDB types.CompilationDatabase) DatabaseDiff {
+	oldEntries := entriesByFile(oldDB)
+	newEntries := entriesByFile(newDB)
+
+	diff := DatabaseDiff{Added: []string{}, Removed: []string{}, Changed: []FileDiff{}}
+	for _, file := range sortedFiles(oldEntries) {
+		newEntry, found := newEntries[file]
+		if !found {
+			diff.Removed = append(diff.Removed, file)
+			continue
+		}
+		if fileDiff, changed := diffEntries(file, oldEntries[file], newEntry); changed {
+			diff.Changed = append(diff.Changed, fileDiff)
+		}
+	}
+	for _, file := range sortedFiles(newEntries) {
+		if _, found := oldEntries[file]; !found {
+			diff.Added = append(diff.Added, file)
+		}
+	}
+
+	return diff
+}
+
+// entriesByFile indexes entries by absolute source file, keeping the first entry for a
+// file like FindEntry
+func entriesByFile(db types.CompilationDatabase) map[string]types.CompilationEntry {
+	entries := make(map[string]types.CompilationEntry, len(db))
+	for _, entry := range db {
+		key := generator.EntryFilePath(entry)
+		if _, found := entries[key]; !found {
+			entries[key] = entry
+		}
+	}
+	return entries
+}
+
+// sortedFiles returns the files of an index in order
+func sortedFiles(entries map[string]types.CompilationEntry) []string {
+	files := make([]string, 0, len(entries))
+	for file := range entries {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// diffEntries compares the entries compiling one file
+func diffEntries(file string, oldEntry, newEntry types.CompilationEntry) (FileDiff, bool) {
+	diff := FileDiff{File: file}
+	oldArgs := generator.EntryArguments(oldEntry)
+	newArgs := generator.EntryArguments(newEntry)
+
+	if oldEntry.Directory != newEntry.Directory {
+		diff.Directory = &ValueChange{Old: oldEntry.Directory, New: newEntry.Directory}
+	}
+	if oldCompiler, newCompiler := firstArg(oldArgs), firstArg(newArgs); oldCompiler != newCompiler {
+		diff.Compiler = &ValueChange{Old: oldCompiler, New: newCompiler}
+	}
+
+	oldFlags := flagTokens(generator.CompileFlags(oldArgs, generator.SourceArgument(oldArgs, oldEntry)))
+	newFlags := flagTokens(generator.CompileFlags(newArgs, generator.SourceArgument(newArgs, newEntry)))
+
+	// Macros defined on both sides with another value are changes, not additions and removals
+	oldDefines := defineValues(oldFlags)
+	newDefines := defineValues(newFlags)
+	changedDefines := make(map[string]bool)
+	for _, flag := range oldFlags {
+		name, oldValue, ok := defineFlag(flag)
+		if !ok || changedDefines[name] {
+			continue
+		}
+		if newValue, found := newDefines[name]; found && newValue != oldValue && oldDefines[name] == oldValue {
+			diff.Defines = append(diff.Defines, DefineChange{Name: name, Old: oldValue, New: newValue})
+			changedDefines[name] = true
+		}
+	}
+	isChangedDefine := func(flag string) bool {
+		name, _, ok := defineFlag(flag)
+		return ok && changedDefines[name]
+	}
+
+	diff.RemovedFlags = subtractFlags(oldFlags, newFlags, isChangedDefine)
+	diff.AddedFlags = subtractFlags(newFlags, oldFlags, isChangedDefine)
+
+	// Compare the order of the flags kept on both sides
+	oldKept := keptFlags(oldFlags, diff.RemovedFlags, isChangedDefine)
+	newKept := keptFlags(newFlags, diff.AddedFlags, isChangedDefine)
+	oldIncludes, oldOthers := splitIncludeFlags(oldKept)
+	newIncludes, newOthers := splitIncludeFlags(newKept)
+	if !equalFlags(oldIncludes, newIncludes) {
+		diff.IncludeOrder = &ListChange{Old: oldIncludes, New: newIncludes}
+	}
+	if !equalFlags(oldOthers, newOthers) {
+		diff.Reordered = &ListChange{Old: oldOthers, New: newOthers}
+	}
+
+	changed := diff.Directory != nil || diff.Compiler != nil || len(diff.AddedFlags) > 0 ||
+		len(diff.RemovedFlags) > 0 || len(diff.Defines) > 0 || diff.IncludeOrder != nil || diff.Reordered != nil
+	return diff, changed
+}
+
+// flagTokens joins each option with its separate value, attaching the values of
+// two-character options (-I inc becomes -Iinc) and keeping a space otherwise
+func flagTokens(flags []string) []string {
+	var tokens []string
+	for _, group := range generator.GroupFlags(flags) {
+		if len(group) == 2 && len(group[0]) == 2 {
+			tokens = append(tokens, group[0]+group[1])
+		} else {
+			tokens = append(tokens, strings.Join(group, " "))
+		}
+	}
+	return tokens
+}
+
+// defineFlag returns the macro name and value of a -D flag token
+func defineFlag(flag string) (name, value string, ok bool) {
+	if !strings.HasPrefix(flag, "-D") || len(flag) == 2 {
+		return "", "", false
+	}
+	name, value, _ = strings.Cut(flag[2:], "=")
+	return name, value, true
+}
+
+// defineValues returns the value each macro ends up with; the last definition wins
+func defineValues(flags []string) map[string]string {
+	values := make(map[string]string)
+	for _, flag := range flags {
+		if name, value, ok := defineFlag(flag); ok {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// subtractFlags returns the flags of a not matched by a flag of b, counting repeated
+// flags, and leaving out flags matched by skip
+func subtractFlags(a, b []string, skip func(string) bool) []string {
+	remaining := make(map[string]int)
+	for _, flag := range b {
+		remaining[flag]++
+	}
+
+	var result []string
+	for _, flag := range a {
+		if skip(flag) {
+			continue
+		}
+		if remaining[flag] > 0 {
+			remaining[flag]--
+			continue
+		}
+		result = append(result, flag)
+	}
+	return result
+}
+
+// keptFlags returns flags without the given unmatched ones and those matched by skip
+func keptFlags(flags, unmatched []string, skip func(string) bool) []string {
+	drop := make(map[string]int)
+	for _, flag := range unmatched {
+		drop[flag]++
+	}
+
+	var kept []string
+	for _, flag := range flags {
+		if skip(flag) {
+			continue
+		}
+		if drop[flag] > 0 {
+			drop[flag]--
+			continue
+		}
+		kept = append(kept, flag)
+	}
+	return kept
+}
+
+// splitIncludeFlags separates include directory flags from the other flags
+func splitIncludeFlags(flags []string) (includes, others []string) {
+	for _, flag := range flags {
+		if isIncludeFlag(flag) {
+			includes = append(includes, flag)
+		} else {
+			others = append(others, flag)
+		}
+	}
+	return includes, others
+}
+
+// isIncludeFlag reports whether a flag token names an include directory
+func isIncludeFlag(flag string) bool {
+	for _, option := range includeOrderOptions {
+		if strings.HasPrefix(flag, option) && len(flag) > len(option) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalFlags reports whether two flag lists are identical
+func equalFlags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// firstArg returns the compiler of an argument list
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
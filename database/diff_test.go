@@ -0,0 +1,68 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestDiff(t *testing.T) {
+	oldDB := types.CompilationDatabase{
+		{Directory: "/p", Command: "gcc -Iinc -I /opt/sdk -DDEBUG -DLEVEL=1 -O0 -Wall -c main.c -o main.o", File: "main.c"},
+		{Directory: "/p", Command: "gcc -c same.c -o same.o", File: "same.c"},
+		{Directory: "/p", Command: "gcc -c gone.c", File: "gone.c"},
+		{Directory: "/p", Command: "gcc -Wall -Wextra -c order.c", File: "order.c"},
+		{Directory: "/p", Command: "gcc -c cc.c", File: "cc.c"},
+	}
+	newDB := types.CompilationDatabase{
+		{Directory: "/p", Arguments: []string{"gcc", "-I", "/opt/sdk", "-Iinc", "-DLEVEL=2", "-O2", "-Wall", "-c", "main.c", "-o", "build/main.o"}, File: "/p/main.c"},
+		{Directory: "/p", Arguments: []string{"gcc", "-c", "same.c", "-o", "same.o", "-MD", "-MF", "same.d"}, File: "same.c"},
+		{Directory: "/p", Command: "gcc -Wextra -Wall -c order.c", File: "order.c"},
+		{Directory: "/p", Command: "clang -c cc.c", File: "cc.c"},
+		{Directory: "/p", Command: "gcc -c new.c", File: "new.c"},
+	}
+
+	diff := Diff(oldDB, newDB)
+
+	if !reflect.DeepEqual(diff.Added, []string{"/p/new.c"}) {
+		t.Errorf("Added = %v, expected [/p/new.c]", diff.Added)
+	}
+	if !reflect.DeepEqual(diff.Removed, []string{"/p/gone.c"}) {
+		t.Errorf("Removed = %v, expected [/p/gone.c]", diff.Removed)
+	}
+
+	expected := []FileDiff{
+		{
+			File:     "/p/cc.c",
+			Compiler: &ValueChange{Old: "gcc", New: "clang"},
+		},
+		{
+			File:         "/p/main.c",
+			AddedFlags:   []string{"-O2"},
+			RemovedFlags: []string{"-DDEBUG", "-O0"},
+			Defines:      []DefineChange{{Name: "LEVEL", Old: "1", New: "2"}},
+			IncludeOrder: &ListChange{Old: []string{"-Iinc", "-I/opt/sdk"}, New: []string{"-I/opt/sdk", "-Iinc"}},
+		},
+		{
+			File:      "/p/order.c",
+			Reordered: &ListChange{Old: []string{"-Wall", "-Wextra"}, New: []string{"-Wextra", "-Wall"}},
+		},
+	}
+	if !reflect.DeepEqual(diff.Changed, expected) {
+		t.Errorf("Changed = %+v\nexpected %+v", diff.Changed, expected)
+	}
+}
+
+func TestDiffEqual(t *testing.T) {
+	db := types.CompilationDatabase{
+		{Directory: "/p", Command: "gcc '-DNAME=\"x y\"' -c a.c", File: "a.c"},
+	}
+	same := types.CompilationDatabase{
+		{Directory: "/p", Arguments: []string{"gcc", "-D", `NAME="x y"`, "-c", "a.c"}, File: "/p/a.c"},
+	}
+
+	if diff := Diff(db, same); !diff.Empty() {
+		t.Errorf("Diff() = %+v, expected no differences", diff)
+	}
+}
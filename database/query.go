@@ -0,0 +1,46 @@
+package database
+
+import (
+	"github.com/gerryqd/yacd/generator"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// QueryResult is the compile information found for a file
+type QueryResult struct {
+	// Entry the information comes from
+	Entry types.CompilationEntry
+
+	// Arguments for the queried file
+	Arguments []string
+
+	// Source file argument within Arguments
+	File string
+
+	// Whether Entry is a translation unit borrowed for a header without its own entry
+	Fallback bool
+}
+
+// Query finds the compile arguments for a file. Headers without an entry of their
+// own fall back to the closest translation unit in the same directory tree.
+func Query(db types.CompilationDatabase, file string) (QueryResult, bool) {
+	if entry, found := FindEntry(db, file); found {
+		args := generator.EntryArguments(entry)
+		return QueryResult{Entry: entry, Arguments: args, File: generator.SourceArgument(args, entry)}, true
+	}
+
+	if !pathutil.IsHeaderFile(file) {
+		return QueryResult{}, false
+	}
+
+	target := pathutil.NormalizePath(pathutil.ResolveRelativePath(pathutil.GetWorkingDirectory(), file))
+	index, found := generator.ClosestEntry(db, target)
+	if !found {
+		return QueryResult{}, false
+	}
+
+	// Compile the header in place of the borrowed translation unit
+	entry := db[index]
+	headerEntry := generator.HeaderEntry(entry, target)
+	return QueryResult{Entry: entry, Arguments: generator.EntryArguments(headerEntry), File: target, Fallback: true}, true
+}
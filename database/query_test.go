@@ -0,0 +1,86 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestQuery(t *testing.T) {
+	db := types.CompilationDatabase{
+		{Directory: "/project/app", Command: "gcc -Iinclude -DAPP -c main.c -o main.o", File: "main.c"},
+		{Directory: "/project/lib", Arguments: []string{"gcc", "-DLIB", "-c", "list.c"}, File: "list.c"},
+		{Directory: "/project/lib", Arguments: []string{"gcc", "-DLIB", "-DHASH", "-c", "hash/hash.c"}, File: "hash/hash.c"},
+	}
+
+	tests := []struct {
+		name             string
+		file             string
+		expectedFound    bool
+		expectedFallback bool
+		expectedArgs     string
+	}{
+		{
+			name:          "Source file with entry",
+			file:          "/project/app/main.c",
+			expectedFound: true,
+			expectedArgs:  "gcc -Iinclude -DAPP -c main.c -o main.o",
+		},
+		{
+			name:          "Source file in arguments form",
+			file:          "/project/lib/hash/hash.c",
+			expectedFound: true,
+			expectedArgs:  "gcc -DLIB -DHASH -c hash/hash.c",
+		},
+		{
+			name:             "Header next to a translation unit",
+			file:             "/project/lib/list.h",
+			expectedFound:    true,
+			expectedFallback: true,
+			expectedArgs:     "gcc -DLIB -c /project/lib/list.h",
+		},
+		{
+			name:             "Header in an include directory",
+			file:             "/project/app/include/config.h",
+			expectedFound:    true,
+			expectedFallback: true,
+			expectedArgs:     "gcc -Iinclude -DAPP -c /project/app/include/config.h -o main.o",
+		},
+		{
+			name:             "Header matched by stem",
+			file:             "/project/lib/include/hash.h",
+			expectedFound:    true,
+			expectedFallback: true,
+			expectedArgs:     "gcc -DLIB -DHASH -c /project/lib/include/hash.h",
+		},
+		{
+			name:          "Header outside the tree",
+			file:          "/usr/include/stdio.h",
+			expectedFound: false,
+		},
+		{
+			name:          "Source file without entry",
+			file:          "/project/app/other.c",
+			expectedFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, found := Query(db, tt.file)
+			if found != tt.expectedFound {
+				t.Fatalf("Query(%s) found = %v, expected %v", tt.file, found, tt.expectedFound)
+			}
+			if !found {
+				return
+			}
+			if result.Fallback != tt.expectedFallback {
+				t.Errorf("Query(%s) fallback = %v, expected %v", tt.file, result.Fallback, tt.expectedFallback)
+			}
+			if args := strings.Join(result.Arguments, " "); args != tt.expectedArgs {
+				t.Errorf("Query(%s) arguments = %s, expected %s", tt.file, args, tt.expectedArgs)
+			}
+		})
+	}
+}
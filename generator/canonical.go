@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/gerryqd/yacd/parser"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// canonicalizeEntry cleans the directory and file paths of an entry as logged, so that
+// "..", "./" and doubled slashes do not end up in the database. The source file is made
+// absolute against the working directory and, with resolveSymlinks, symbolic links in
+// absolute paths are evaluated. The source argument is rewritten to the same path as the
+// source file, so that consumers can find it by comparing with "file". The output and
+// include directory arguments are cleaned; relative ones stay relative to the working directory.
+func canonicalizeEntry(entry types.MakeLogEntry, resolveSymlinks bool) types.MakeLogEntry {
+	canonical := func(path string) string {
+		if path == "" {
+			return path
+		}
+		if resolveSymlinks && pathutil.IsAbsolutePath(path) {
+			return pathutil.EvalSymlinks(path)
+		}
+		return pathutil.NormalizePath(path)
+	}
+
+	sourceFile := entry.SourceFile
+	outputFile := entry.OutputFile
+
+	entry.WorkingDir = canonical(entry.WorkingDir)
+	if pathutil.IsAbsolutePath(entry.WorkingDir) {
+		entry.SourceFile = canonical(pathutil.ResolveRelativePath(entry.WorkingDir, entry.SourceFile))
+	} else {
+		entry.SourceFile = canonical(entry.SourceFile)
+	}
+	entry.OutputFile = canonical(entry.OutputFile)
+
+	// Only the logged source and output paths are rewritten for options naming them
+	canonicalSource := replacePath(sourceFile, entry.SourceFile)
+	canonicalOutput := replacePath(outputFile, entry.OutputFile)
+
+	profile := parser.DetectArgsProfile(entry.Args)
+	args := make([]string, len(entry.Args))
+	copy(args, entry.Args)
+	for i := 1; i < len(args); i++ {
+		if args[i] == sourceFile {
+			args[i] = canonicalSource(args[i])
+			continue
+		}
+		args[i] = canonicalOptionPath(args[i-1], args[i], profile.SourceOptions, canonicalSource)
+		args[i] = canonicalOptionPath(args[i-1], args[i], profile.OutputOptions, canonicalOutput)
+		args[i] = canonicalOptionPath(args[i-1], args[i], profile.IncludeOptions, canonical)
+	}
+	entry.Args = args
+
+	return entry
+}
+
+// canonicalOptionPath rewrites the path of an option, given either attached to the
+// option (-I../inc) or as the argument after it
+func canonicalOptionPath(previous, arg string, options []string, canonical func(string) string) string {
+	if isOption(previous, options) {
+		return canonical(arg)
+	}
+	for _, option := range options {
+		if strings.HasPrefix(arg, option) && len(arg) > len(option) {
+			return option + canonical(strings.TrimPrefix(arg, option))
+		}
+	}
+	return arg
+}
+
+// replacePath returns a function replacing the path logged with replacement, leaving
+// other paths unchanged
+func replacePath(logged, replacement string) func(string) string {
+	return func(value string) string {
+		if value == logged {
+			return replacement
+		}
+		return value
+	}
+}
+
+// isOption reports whether arg is one of options
+func isOption(arg string, options []string) bool {
+	for _, option := range options {
+		if arg == option {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPathStyle rewrites the Windows drive paths in an entry's directory, files and
+// arguments to the given style
+func applyPathStyle(entry types.MakeLogEntry, pathStyle string) types.MakeLogEntry {
+	if pathStyle == "" {
+		return entry
+	}
+	style := pathutil.PathStyle(pathStyle)
+
+	entry.WorkingDir = pathutil.ConvertPathStyle(entry.WorkingDir, style)
+	entry.SourceFile = pathutil.ConvertPathStyle(entry.SourceFile, style)
+	entry.OutputFile = pathutil.ConvertPathStyle(entry.OutputFile, style)
+
+	args := make([]string, len(entry.Args))
+	for i, arg := range entry.Args {
+		args[i] = pathutil.ConvertArgPathStyle(arg, style)
+	}
+	entry.Args = args
+
+	return entry
+}
@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestCanonicalizeEntry(t *testing.T) {
+	tests := []struct {
+		name      string
+		entry     types.MakeLogEntry
+		directory string
+		file      string
+		output    string
+		args      string
+	}{
+		{
+			name: "Relative source made absolute",
+			entry: types.MakeLogEntry{
+				WorkingDir: "/project/build/",
+				Args:       []string{"gcc", "-c", "../src//main.c", "-o", "./main.o"},
+				SourceFile: "../src//main.c",
+				OutputFile: "./main.o",
+			},
+			directory: "/project/build",
+			file:      "/project/src/main.c",
+			output:    "main.o",
+			args:      "gcc -c /project/src/main.c -o main.o",
+		},
+		{
+			name: "Absolute paths and include directories",
+			entry: types.MakeLogEntry{
+				WorkingDir: "/project/./build",
+				Args:       []string{"gcc", "-I../inc/", "-isystem", "/sdk//include", "-c", "/project/build/../src/main.c", "-o/project/build/./main.o"},
+				SourceFile: "/project/build/../src/main.c",
+				OutputFile: "/project/build/./main.o",
+			},
+			directory: "/project/build",
+			file:      "/project/src/main.c",
+			output:    "/project/build/main.o",
+			args:      "gcc -I../inc -isystem /sdk/include -c /project/src/main.c -o/project/build/main.o",
+		},
+		{
+			name: "MSVC options",
+			entry: types.MakeLogEntry{
+				WorkingDir: `C:\work\build\`,
+				Args:       []string{"cl", "/c", `/IC:\work\build\..\inc`, `/FoC:\work\build\.\main.obj`, `C:\work\src\\main.c`},
+				SourceFile: `C:\work\src\\main.c`,
+				OutputFile: `C:\work\build\.\main.obj`,
+			},
+			directory: "C:/work/build",
+			file:      "C:/work/src/main.c",
+			output:    "C:/work/build/main.obj",
+			args:      "cl /c /IC:/work/inc /FoC:/work/build/main.obj C:/work/src/main.c",
+		},
+		{
+			name: "Other arguments untouched",
+			entry: types.MakeLogEntry{
+				WorkingDir: "/project",
+				Args:       []string{"gcc", "-DPATH=./x//y", "-include", "./config.h", "-c", "main.c"},
+				SourceFile: "main.c",
+			},
+			directory: "/project",
+			file:      "/project/main.c",
+			output:    "",
+			args:      "gcc -DPATH=./x//y -include ./config.h -c /project/main.c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := canonicalizeEntry(tt.entry, false)
+			if toSlash(result.WorkingDir) != tt.directory {
+				t.Errorf("WorkingDir = %s, expected %s", result.WorkingDir, tt.directory)
+			}
+			if toSlash(result.SourceFile) != tt.file {
+				t.Errorf("SourceFile = %s, expected %s", result.SourceFile, tt.file)
+			}
+			if toSlash(result.OutputFile) != tt.output {
+				t.Errorf("OutputFile = %s, expected %s", result.OutputFile, tt.output)
+			}
+			if args := toSlash(strings.Join(result.Args, " ")); args != tt.args {
+				t.Errorf("Args = %s, expected %s", args, tt.args)
+			}
+		})
+	}
+}
+
+// toSlash converts both separators so that results compare equal on every host
+func toSlash(path string) string {
+	return strings.ReplaceAll(filepath.ToSlash(path), `\`, "/")
+}
+
+func TestCanonicalizeEntryResolveSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need extra privileges on Windows")
+	}
+
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp directory: %v", err)
+	}
+	real := filepath.Join(tempDir, "real")
+	link := filepath.Join(tempDir, "link")
+	if err := os.MkdirAll(filepath.Join(real, "inc"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	entry := types.MakeLogEntry{
+		WorkingDir: link,
+		Args:       []string{"gcc", "-I" + link + "/inc", "-c", "main.c"},
+		SourceFile: "main.c",
+	}
+
+	result := canonicalizeEntry(entry, true)
+	if result.WorkingDir != real {
+		t.Errorf("WorkingDir = %s, expected %s", result.WorkingDir, real)
+	}
+	if result.SourceFile != filepath.Join(real, "main.c") {
+		t.Errorf("SourceFile = %s, expected %s", result.SourceFile, filepath.Join(real, "main.c"))
+	}
+	if result.Args[1] != "-I"+filepath.Join(real, "inc") {
+		t.Errorf("Include argument = %s, expected -I%s", result.Args[1], filepath.Join(real, "inc"))
+	}
+
+	// Without the option the symlinked path is kept
+	if result := canonicalizeEntry(entry, false); result.WorkingDir != link {
+		t.Errorf("WorkingDir = %s, expected %s", result.WorkingDir, link)
+	}
+}
+
+func TestGenerateCompilationDatabasePathStyle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("expected paths use forward slashes")
+	}
+
+	entries := []types.MakeLogEntry{
+		{
+			WorkingDir: "/c/work/build",
+			Args:       []string{"gcc", "-I/c/work/inc", "-c", "../src/main.c", "-o", "main.o"},
+			SourceFile: "../src/main.c",
+			OutputFile: "main.o",
+		},
+	}
+
+	tests := []struct {
+		style     string
+		directory string
+		file      string
+		command   string
+	}{
+		{"windows", `C:\work\build`, `C:\work\src\main.c`, `gcc '-IC:\work\inc' -c 'C:\work\src\main.c' -o main.o`},
+		{"cygwin", "/cygdrive/c/work/build", "/cygdrive/c/work/src/main.c", "gcc -I/cygdrive/c/work/inc -c /cygdrive/c/work/src/main.c -o main.o"},
+		{"", "/c/work/build", "/c/work/src/main.c", "gcc -I/c/work/inc -c /c/work/src/main.c -o main.o"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			result, _, err := GenerateCompilationDatabase(entries, &types.ParseOptions{PathStyle: tt.style})
+			if err != nil {
+				t.Fatalf("GenerateCompilationDatabase() unexpected error: %v", err)
+			}
+			if result[0].Directory != tt.directory {
+				t.Errorf("Directory = %s, expected %s", result[0].Directory, tt.directory)
+			}
+			if result[0].File != tt.file {
+				t.Errorf("File = %s, expected %s", result[0].File, tt.file)
+			}
+			if result[0].Command != tt.command {
+				t.Errorf("Command = %s, expected %s", result[0].Command, tt.command)
+			}
+		})
+	}
+}
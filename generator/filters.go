@@ -0,0 +1,249 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gerryqd/yacd/parser"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// applyPathMaps rewrites the path prefixes of an entry's directory, files and arguments
+func applyPathMaps(entry types.MakeLogEntry, pathMaps []types.PathMap) types.MakeLogEntry {
+	if len(pathMaps) == 0 {
+		return entry
+	}
+
+	entry.WorkingDir = mapPath(entry.WorkingDir, pathMaps)
+	entry.SourceFile = mapPath(entry.SourceFile, pathMaps)
+	entry.OutputFile = mapPath(entry.OutputFile, pathMaps)
+
+	profile := parser.DetectArgsProfile(entry.Args)
+	var options []string
+	options = append(options, profile.IncludeOptions...)
+	options = append(options, profile.OutputOptions...)
+	options = append(options, profile.SourceOptions...)
+	options = append(options, profile.PathOptions...)
+
+	args := make([]string, len(entry.Args))
+	for i, arg := range entry.Args {
+		args[i] = mapArgPath(arg, options, pathMaps)
+	}
+	entry.Args = args
+
+	return entry
+}
+
+// mapArgPath rewrites the path prefix of an argument, or of a path attached to one of
+// options, directly (-I/src/include) or after "=" (--sysroot=/src/sdk)
+func mapArgPath(arg string, options []string, pathMaps []types.PathMap) string {
+	if mapped := mapPath(arg, pathMaps); mapped != arg {
+		return mapped
+	}
+
+	for _, option := range options {
+		if !strings.HasPrefix(arg, option) || len(arg) == len(option) {
+			continue
+		}
+		value := strings.TrimPrefix(arg, option)
+		separator := ""
+		if strings.HasPrefix(value, "=") {
+			separator, value = "=", value[1:]
+		}
+		if mapped := mapPath(value, pathMaps); mapped != value {
+			return option + separator + mapped
+		}
+	}
+
+	return arg
+}
+
+// mapPath rewrites the first path prefix matching the start of a path
+func mapPath(value string, pathMaps []types.PathMap) string {
+	for _, pathMap := range pathMaps {
+		if pathMap.From == "" || !strings.HasPrefix(value, pathMap.From) {
+			continue
+		}
+
+		// Only match whole path components
+		rest := value[len(pathMap.From):]
+		if rest != "" && !strings.HasPrefix(rest, "/") && !strings.HasSuffix(pathMap.From, "/") {
+			continue
+		}
+
+		return pathMap.To + rest
+	}
+
+	return value
+}
+
+// Prefixes of --include/--exclude rules
+const (
+	filterDirectoryPrefix = "dir:"
+	filterFilePrefix      = "file:"
+	filterRegexPrefix     = "re:"
+)
+
+// filterRule is a single --include or --exclude rule. Rules match the absolute source
+// file by default, or the working directory with the "dir:" prefix; patterns are globs
+// unless prefixed with "re:".
+type filterRule struct {
+	// Rule as given by the user
+	spec string
+
+	// Whether the rule keeps matching entries rather than removing them
+	include bool
+
+	// Whether the rule matches the directory rather than the file
+	directory bool
+
+	// Compiled glob pattern, when regex is nil
+	glob *regexp.Regexp
+
+	// Regular expression pattern
+	regex *regexp.Regexp
+}
+
+// newFilterRules parses include and exclude rules
+func newFilterRules(includes, excludes []string) ([]filterRule, error) {
+	var rules []filterRule
+	for _, spec := range includes {
+		rule, err := parseFilterRule(spec, true)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	for _, spec := range excludes {
+		rule, err := parseFilterRule(spec, false)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseFilterRule parses a rule of the form [dir:|file:][re:]PATTERN
+func parseFilterRule(spec string, include bool) (filterRule, error) {
+	rule := filterRule{spec: spec, include: include}
+
+	pattern := spec
+	if strings.HasPrefix(pattern, filterDirectoryPrefix) {
+		rule.directory = true
+		pattern = strings.TrimPrefix(pattern, filterDirectoryPrefix)
+	} else {
+		pattern = strings.TrimPrefix(pattern, filterFilePrefix)
+	}
+
+	if strings.HasPrefix(pattern, filterRegexPrefix) {
+		regex, err := regexp.Compile(strings.TrimPrefix(pattern, filterRegexPrefix))
+		if err != nil {
+			return rule, errorutil.CreateInvalidArgumentError(spec, err.Error())
+		}
+		rule.regex = regex
+	} else {
+		rule.glob = pathutil.GlobRegexp(pattern)
+	}
+
+	if pattern == "" {
+		return rule, errorutil.CreateInvalidArgumentError(spec, "empty filter pattern")
+	}
+	return rule, nil
+}
+
+// matches reports whether the rule matches an entry's absolute file or directory.
+// Directory globs also match below the matched directory.
+func (r filterRule) matches(file, directory string) bool {
+	target := pathutil.ToSlashPath(file)
+	if r.directory {
+		target = pathutil.ToSlashPath(directory)
+	}
+
+	if r.regex != nil {
+		return r.regex.MatchString(target)
+	}
+	if !r.directory {
+		return r.glob.MatchString(target)
+	}
+
+	for {
+		if r.glob.MatchString(target) {
+			return true
+		}
+		parent := path.Dir(target)
+		if parent == target {
+			return false
+		}
+		target = parent
+	}
+}
+
+// entryFilter applies include and exclude rules and counts the entries each rule removed
+type entryFilter struct {
+	// Parsed rules, includes first
+	rules []filterRule
+
+	// Number of entries removed by each exclude rule
+	removed []int
+
+	// Number of entries that matched no include rule
+	notIncluded int
+
+	// Whether any include rule was given
+	hasIncludes bool
+}
+
+// newEntryFilter creates a filter from the include and exclude rules
+func newEntryFilter(includes, excludes []string) (*entryFilter, error) {
+	rules, err := newFilterRules(includes, excludes)
+	if err != nil {
+		return nil, err
+	}
+	return &entryFilter{rules: rules, removed: make([]int, len(rules)), hasIncludes: len(includes) > 0}, nil
+}
+
+// keep reports whether an entry passes the filter. An entry is kept when it matches
+// an include rule (if any are given) and no exclude rule.
+func (f *entryFilter) keep(entry types.MakeLogEntry) bool {
+	if len(f.rules) == 0 {
+		return true
+	}
+
+	directory := pathutil.NormalizePath(entry.WorkingDir)
+	file := pathutil.NormalizePath(pathutil.ResolveRelativePath(directory, entry.SourceFile))
+
+	included := !f.hasIncludes
+	for i, rule := range f.rules {
+		if !rule.matches(file, directory) {
+			continue
+		}
+		if rule.include {
+			included = true
+			continue
+		}
+		f.removed[i]++
+		return false
+	}
+
+	if !included {
+		f.notIncluded++
+	}
+	return included
+}
+
+// printSummary prints how many entries each rule removed
+func (f *entryFilter) printSummary() {
+	for i, rule := range f.rules {
+		if !rule.include {
+			fmt.Printf("Filter --exclude %s removed %d entries\n", rule.spec, f.removed[i])
+		}
+	}
+	if f.hasIncludes {
+		fmt.Printf("Filter --include removed %d entries matching no include rule\n", f.notIncluded)
+	}
+}
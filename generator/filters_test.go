@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gerryqd/yacd/parser"
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestMapPath(t *testing.T) {
+	pathMaps := []types.PathMap{
+		{From: "/build/src", To: "/home/dev/project"},
+		{From: "/opt/sdk/", To: "/usr/local/sdk/"},
+	}
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"/build/src", "/home/dev/project"},
+		{"/build/src/main.c", "/home/dev/project/main.c"},
+		{"-I/build/src/include", "-I/home/dev/project/include"},
+		{"-isystem/build/src/include", "-isystem/home/dev/project/include"},
+		{"--sysroot=/opt/sdk/arm", "--sysroot=/usr/local/sdk/arm"},
+		{"/build/src2/main.c", "/build/src2/main.c"},
+		{"main.c", "main.c"},
+		{"foo/build/src", "foo/build/src"},
+		// The prefix must start the path, not just appear in it
+		{"-I/x/build/src/inc", "-I/x/build/src/inc"},
+		{"-DDIR=/build/src", "-DDIR=/build/src"},
+	}
+
+	options := append(append([]string(nil), parser.GCCProfile.IncludeOptions...), parser.GCCProfile.PathOptions...)
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if result := mapArgPath(tt.value, options, pathMaps); result != tt.expected {
+				t.Errorf("mapArgPath(%q) = %q, expected %q", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEntryFilter(t *testing.T) {
+	entries := []types.MakeLogEntry{
+		{WorkingDir: "/project", SourceFile: "src/main.c"},
+		{WorkingDir: "/project", SourceFile: "third_party/zlib/inflate.c"},
+		{WorkingDir: "/project/build/gen", SourceFile: "harness_test.c"},
+		{WorkingDir: "/project/tests", SourceFile: "../src/util.c"},
+		{WorkingDir: "/project", SourceFile: "src/util_test.cpp"},
+	}
+
+	tests := []struct {
+		name            string
+		includes        []string
+		excludes        []string
+		expectedKept    []int
+		expectedRemoved []int
+		expectError     bool
+	}{
+		{
+			name:         "No rules",
+			expectedKept: []int{0, 1, 2, 3, 4},
+		},
+		{
+			name:            "File globs",
+			excludes:        []string{"third_party/**", "file:*_test.*"},
+			expectedKept:    []int{0, 3},
+			expectedRemoved: []int{1, 2},
+		},
+		{
+			name:            "Directory glob matches below the directory",
+			excludes:        []string{"dir:build"},
+			expectedKept:    []int{0, 1, 3, 4},
+			expectedRemoved: []int{1},
+		},
+		{
+			name:            "Regular expressions",
+			excludes:        []string{`re:_test\.(c|cpp)$`, "dir:re:/tests$"},
+			expectedKept:    []int{0, 1},
+			expectedRemoved: []int{2, 1},
+		},
+		{
+			name:            "Files resolved against the directory",
+			excludes:        []string{"/project/src/util.c"},
+			expectedKept:    []int{0, 1, 2, 4},
+			expectedRemoved: []int{1},
+		},
+		{
+			name:            "Includes and excludes",
+			includes:        []string{"src/**"},
+			excludes:        []string{"*_test.*"},
+			expectedKept:    []int{0, 3},
+			expectedRemoved: []int{2},
+		},
+		{
+			name:        "Invalid regular expression",
+			excludes:    []string{"re:("},
+			expectError: true,
+		},
+		{
+			name:        "Empty pattern",
+			includes:    []string{"dir:"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newEntryFilter(tt.includes, tt.excludes)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("newEntryFilter() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newEntryFilter() unexpected error: %v", err)
+			}
+
+			var kept []int
+			for i, entry := range entries {
+				if filter.keep(entry) {
+					kept = append(kept, i)
+				}
+			}
+			if fmt.Sprint(kept) != fmt.Sprint(tt.expectedKept) {
+				t.Errorf("kept entries = %v, expected %v", kept, tt.expectedKept)
+			}
+
+			// Counts of the exclude rules, which follow the include rules
+			removed := filter.removed[len(tt.includes):]
+			if len(tt.expectedRemoved) > 0 && fmt.Sprint(removed) != fmt.Sprint(tt.expectedRemoved) {
+				t.Errorf("removed counts = %v, expected %v", removed, tt.expectedRemoved)
+			}
+			if excluded := len(entries) - len(tt.expectedKept) - sum(tt.expectedRemoved); filter.notIncluded != excluded {
+				t.Errorf("entries matching no include rule = %d, expected %d", filter.notIncluded, excluded)
+			}
+		})
+	}
+}
+
+// sum adds up a list of counts
+func sum(values []int) int {
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/gerryqd/yacd/parser"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// Options dropped from compile_flags.txt style output, with whether they take a value
+var nonSemanticOptions = map[string]bool{
+	"-c":   false,
+	"-o":   true,
+	"-MD":  false,
+	"-MMD": false,
+	"-MP":  false,
+	"-MF":  true,
+	"-MT":  true,
+	"-MQ":  true,
+}
+
+// CompileFlags returns the flags that affect how a file is parsed: the compiler,
+// the source file, the output file and dependency-generation options are removed
+func CompileFlags(args []string, sourceFile string) []string {
+	var flags []string
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == sourceFile {
+			continue
+		}
+		if takesValue, ok := nonSemanticOptions[arg]; ok {
+			if takesValue {
+				i++
+			}
+			continue
+		}
+		if hasAttachedValue(arg, "-o", "-MF", "-MT", "-MQ") {
+			continue
+		}
+		flags = append(flags, arg)
+	}
+
+	return flags
+}
+
+// Options whose value may be given as the next argument
+var separateValueOptions = []string{"-isystem", "-iquote", "-idirafter", "-imacros", "-include", "-I", "-D", "-U", "-x", "-target"}
+
+// GroupFlags groups each option with a separate value together with that value,
+// so that ["-I", "inc", "-Wall"] becomes [["-I", "inc"], ["-Wall"]]
+func GroupFlags(flags []string) [][]string {
+	var groups [][]string
+	for i := 0; i < len(flags); i++ {
+		if isSeparateValueOption(flags[i]) && i+1 < len(flags) {
+			groups = append(groups, []string{flags[i], flags[i+1]})
+			i++
+			continue
+		}
+		groups = append(groups, []string{flags[i]})
+	}
+	return groups
+}
+
+// splitFlagGroup returns the option and value of a flag group, handling both
+// separate ("-D", "X") and attached ("-DX") values
+func splitFlagGroup(group []string) (option, value string) {
+	if len(group) == 2 {
+		return group[0], group[1]
+	}
+	for _, option := range separateValueOptions {
+		if strings.HasPrefix(group[0], option) && len(group[0]) > len(option) {
+			return option, strings.TrimPrefix(group[0], option)
+		}
+	}
+	return group[0], ""
+}
+
+// absoluteIncludeFlags resolves relative include directories against directory
+func absoluteIncludeFlags(flags []string, directory string) []string {
+	resolved := make([]string, len(flags))
+	copy(resolved, flags)
+
+	for i := 0; i < len(resolved); i++ {
+		for _, option := range parser.GCCProfile.IncludeOptions {
+			if !strings.HasPrefix(resolved[i], option) {
+				continue
+			}
+			if resolved[i] == option {
+				if i+1 < len(resolved) {
+					resolved[i+1] = absoluteDirectory(directory, resolved[i+1])
+					i++
+				}
+			} else {
+				resolved[i] = option + absoluteDirectory(directory, strings.TrimPrefix(resolved[i], option))
+			}
+			break
+		}
+	}
+
+	return resolved
+}
+
+// absoluteDirectory resolves dir against base and cleans it
+func absoluteDirectory(base, dir string) string {
+	return pathutil.NormalizePath(pathutil.ResolveRelativePath(base, dir))
+}
+
+// isSeparateValueOption reports whether arg is an option that takes the next argument as value
+func isSeparateValueOption(arg string) bool {
+	for _, option := range separateValueOptions {
+		if arg == option {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAttachedValue reports whether arg is one of options with its value attached
+func hasAttachedValue(arg string, options ...string) bool {
+	for _, option := range options {
+		if strings.HasPrefix(arg, option) && len(arg) > len(option) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShellQuote quotes an argument for a POSIX shell when needed
+func ShellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\n\"'\\$`!*?[]{}()<>|&;#~") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// ShellJoin joins arguments into a command line that a POSIX shell splits back into the same arguments
+func ShellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
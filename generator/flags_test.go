@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/parser"
+)
+
+func TestCompileFlags(t *testing.T) {
+	args := []string{
+		"arm-none-eabi-gcc", "-c", "-mcpu=cortex-m0", "-DNDEBUG", "-ICore/Inc",
+		"-MMD", "-MP", "-MFbuild/main.d", "-MT", "build/main.o",
+		"user/app/main.c", "-o", "build/main.o",
+	}
+	expected := []string{"-mcpu=cortex-m0", "-DNDEBUG", "-ICore/Inc"}
+
+	result := CompileFlags(args, "user/app/main.c")
+	if strings.Join(result, "|") != strings.Join(expected, "|") {
+		t.Errorf("CompileFlags() = %v, expected %v", result, expected)
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Plain arguments",
+			args:     []string{"gcc", "-c", "main.c"},
+			expected: "gcc -c main.c",
+		},
+		{
+			name:     "Argument with space",
+			args:     []string{"gcc", "-DMESSAGE=Hello World", "-c", "main.c"},
+			expected: "gcc '-DMESSAGE=Hello World' -c main.c",
+		},
+		{
+			name:     "Argument with quotes",
+			args:     []string{"gcc", `-DNAME="it's"`},
+			expected: `gcc '-DNAME="it'\''s"'`,
+		},
+		{
+			name:     "Empty argument",
+			args:     []string{"gcc", ""},
+			expected: "gcc ''",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ShellJoin(tt.args)
+			if result != tt.expected {
+				t.Errorf("ShellJoin(%v) = %s, expected %s", tt.args, result, tt.expected)
+			}
+		})
+	}
+}
+
+// Header entries and relative paths split commands written by ShellJoin and join them
+// again, so the splitter must give back the same arguments
+func TestShellJoinSplitsBack(t *testing.T) {
+	args := []string{"gcc", `-DMSG="hello world"`, `-DNAME="it's"`, `-IC:\work\inc`, "-DEMPTY=", "", "-c", "my file.c", "src/é"}
+	result := parser.SplitCommandLine(ShellJoin(args))
+	if !reflect.DeepEqual(result, args) {
+		t.Errorf("SplitCommandLine(ShellJoin(%q)) = %q", args, result)
+	}
+}
@@ -0,0 +1,314 @@
+package generator
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// DefaultOutputFormat is the JSON compilation database format
+const DefaultOutputFormat = "json"
+
+// DatabaseWriter writes a compilation database in one output format
+type DatabaseWriter interface {
+	// DefaultFileName returns the file name used when no output path is given
+	DefaultFileName() string
+
+	// Write writes the database to w
+	Write(w io.Writer, db []types.CompilationEntry) error
+}
+
+// Writers for each output format
+var databaseWriters = map[string]DatabaseWriter{
+	"json":          jsonWriter{},
+	"bear":          bearWriter{},
+	"compile-flags": compileFlagsWriter{},
+	"csv":           tableWriter{comma: ',', fileName: "compile_commands.csv"},
+	"tsv":           tableWriter{comma: '\t', fileName: "compile_commands.tsv"},
+	"ninja":         ninjaWriter{},
+	"cmake":         cmakeWriter{},
+}
+
+// OutputFormats returns the names of the supported output formats
+func OutputFormats() []string {
+	formats := make([]string, 0, len(databaseWriters))
+	for format := range databaseWriters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// NewDatabaseWriter returns the writer for an output format; an empty format selects JSON
+func NewDatabaseWriter(format string) (DatabaseWriter, error) {
+	if format == "" {
+		format = DefaultOutputFormat
+	}
+	writer, ok := databaseWriters[format]
+	if !ok {
+		return nil, errorutil.CreateInvalidArgumentError(format, "unsupported output format, expected one of: "+strings.Join(OutputFormats(), ", "))
+	}
+	return writer, nil
+}
+
+// jsonWriter writes the standard compile_commands.json
+type jsonWriter struct{}
+
+// DefaultFileName returns compile_commands.json
+func (jsonWriter) DefaultFileName() string {
+	return "compile_commands.json"
+}
+
+// Write writes the entries as an indented JSON array
+func (jsonWriter) Write(w io.Writer, db []types.CompilationEntry) error {
+	// An empty database is written as [] rather than null
+	if db == nil {
+		db = []types.CompilationEntry{}
+	}
+	return writeJSON(w, db)
+}
+
+// bearEntry is an entry in the layout written by Bear: arguments, absolute file and output
+type bearEntry struct {
+	Arguments []string `json:"arguments"`
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Output    string   `json:"output,omitempty"`
+}
+
+// bearWriter writes compile_commands.json in the layout produced by Bear
+type bearWriter struct{}
+
+// DefaultFileName returns compile_commands.json
+func (bearWriter) DefaultFileName() string {
+	return "compile_commands.json"
+}
+
+// Write writes the entries with argument arrays and resolved file and output paths
+func (bearWriter) Write(w io.Writer, db []types.CompilationEntry) error {
+	entries := make([]bearEntry, 0, len(db))
+	for _, entry := range db {
+		bear := bearEntry{
+			Arguments: EntryArguments(entry),
+			Directory: entry.Directory,
+			File:      EntryFilePath(entry),
+		}
+		if entry.Output != "" {
+			bear.Output = pathutil.NormalizePath(pathutil.ResolveRelativePath(entry.Directory, entry.Output))
+		}
+		entries = append(entries, bear)
+	}
+	return writeJSON(w, entries)
+}
+
+// writeJSON writes a value as indented JSON followed by a newline
+func writeJSON(w io.Writer, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errorutil.WrapError(err, "failed to marshal compilation database to JSON")
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// compileFlagsWriter writes compile_flags.txt with the flags shared by every entry
+type compileFlagsWriter struct{}
+
+// DefaultFileName returns compile_flags.txt
+func (compileFlagsWriter) DefaultFileName() string {
+	return "compile_flags.txt"
+}
+
+// Write writes one argument per line. Include directories are made absolute so that
+// they do not depend on the directory of each entry.
+func (compileFlagsWriter) Write(w io.Writer, db []types.CompilationEntry) error {
+	var common [][]string
+	for i, entry := range db {
+		args := EntryArguments(entry)
+		groups := GroupFlags(absoluteIncludeFlags(CompileFlags(args, SourceArgument(args, entry)), entry.Directory))
+		if i == 0 {
+			common = groups
+			continue
+		}
+
+		present := make(map[string]bool)
+		for _, group := range groups {
+			present[strings.Join(group, "\x00")] = true
+		}
+		var kept [][]string
+		for _, group := range common {
+			if present[strings.Join(group, "\x00")] {
+				kept = append(kept, group)
+			}
+		}
+		common = kept
+	}
+
+	for _, group := range common {
+		for _, arg := range group {
+			if _, err := fmt.Fprintln(w, arg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tableWriter writes one row per entry as CSV or TSV
+type tableWriter struct {
+	// Field separator
+	comma rune
+
+	// Default output file name
+	fileName string
+}
+
+// DefaultFileName returns the file name for the separator
+func (t tableWriter) DefaultFileName() string {
+	return t.fileName
+}
+
+// Write writes a header and the directory, file, output, compiler and arguments of each entry
+func (t tableWriter) Write(w io.Writer, db []types.CompilationEntry) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = t.comma
+
+	if err := writer.Write([]string{"directory", "file", "output", "compiler", "arguments"}); err != nil {
+		return err
+	}
+	for _, entry := range db {
+		args := EntryArguments(entry)
+		compiler := ""
+		if len(args) > 0 {
+			compiler, args = args[0], args[1:]
+		}
+		if err := writer.Write([]string{entry.Directory, entry.File, entry.Output, compiler, ShellJoin(args)}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ninjaWriter writes a build.ninja with one edge per entry
+type ninjaWriter struct{}
+
+// DefaultFileName returns build.ninja
+func (ninjaWriter) DefaultFileName() string {
+	return "build.ninja"
+}
+
+// Write writes a compile rule and a build edge running each entry's command in its directory.
+// Entries without an output compile to the source file name with ".o" appended.
+func (ninjaWriter) Write(w io.Writer, db []types.CompilationEntry) error {
+	var b strings.Builder
+	b.WriteString("# Generated by yacd from a compilation database\n\n")
+	b.WriteString("rule compile\n")
+	b.WriteString("  command = cd $cwd && $cmd\n")
+	b.WriteString("  description = Compiling $in\n")
+
+	for _, entry := range db {
+		output := entry.Output
+		if output == "" {
+			output = entry.File + ".o"
+		}
+		output = pathutil.NormalizePath(pathutil.ResolveRelativePath(entry.Directory, output))
+
+		fmt.Fprintf(&b, "\nbuild %s: compile %s\n", ninjaEscapePath(output), ninjaEscapePath(EntryFilePath(entry)))
+		fmt.Fprintf(&b, "  cwd = %s\n", ninjaEscape(ShellQuote(entry.Directory)))
+		fmt.Fprintf(&b, "  cmd = %s\n", ninjaEscape(ShellJoin(EntryArguments(entry))))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ninjaEscape escapes a variable value for a ninja file
+func ninjaEscape(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// ninjaEscapePath escapes a path used in a build statement
+func ninjaEscapePath(path string) string {
+	return strings.NewReplacer("$", "$$", " ", "$ ", ":", "$:").Replace(path)
+}
+
+// cmakeWriter writes a CMake file with an object library and per-source properties
+type cmakeWriter struct{}
+
+// DefaultFileName returns yacd.cmake
+func (cmakeWriter) DefaultFileName() string {
+	return "yacd.cmake"
+}
+
+// Write writes an object library listing every source, with the include directories,
+// definitions and remaining options of each entry as source file properties
+func (cmakeWriter) Write(w io.Writer, db []types.CompilationEntry) error {
+	var b strings.Builder
+	b.WriteString("# Generated by yacd from a compilation database.\n")
+	b.WriteString("# Include this file from a CMakeLists.txt (CMake 3.13 or newer).\n\n")
+
+	b.WriteString("add_library(yacd_sources OBJECT\n")
+	for _, entry := range db {
+		fmt.Fprintf(&b, "  %s\n", cmakeQuote(EntryFilePath(entry)))
+	}
+	b.WriteString(")\n")
+
+	for _, entry := range db {
+		var includes, definitions, options []string
+		args := EntryArguments(entry)
+		for _, group := range GroupFlags(absoluteIncludeFlags(CompileFlags(args, SourceArgument(args, entry)), entry.Directory)) {
+			option, value := splitFlagGroup(group)
+			switch option {
+			case "-I", "-isystem", "-iquote", "-idirafter":
+				includes = append(includes, value)
+			case "-D":
+				definitions = append(definitions, value)
+			default:
+				options = append(options, group...)
+			}
+		}
+
+		fmt.Fprintf(&b, "\nset_source_files_properties(%s PROPERTIES\n", cmakeQuote(EntryFilePath(entry)))
+		if len(includes) > 0 {
+			fmt.Fprintf(&b, "  INCLUDE_DIRECTORIES %s\n", cmakeList(includes))
+		}
+		if len(definitions) > 0 {
+			fmt.Fprintf(&b, "  COMPILE_DEFINITIONS %s\n", cmakeList(definitions))
+		}
+		fmt.Fprintf(&b, "  COMPILE_OPTIONS %s\n", cmakeList(options))
+		b.WriteString(")\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// cmakeQuote quotes a CMake argument
+func cmakeQuote(value string) string {
+	return `"` + cmakeEscape(value) + `"`
+}
+
+// cmakeList quotes values as a single CMake list argument
+func cmakeList(values []string) string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = strings.ReplaceAll(cmakeEscape(value), ";", `\;`)
+	}
+	return `"` + strings.Join(escaped, ";") + `"`
+}
+
+// cmakeEscape escapes the characters that are special in a quoted CMake argument
+func cmakeEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`).Replace(value)
+}
@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestDatabaseWriters(t *testing.T) {
+	db := []types.CompilationEntry{
+		{
+			Directory: "/project",
+			Arguments: []string{"gcc", "-Iinc", "-D", "MSG=hello world", "-Wall", "-c", "src/main.c", "-o", "build/main.o"},
+			File:      "src/main.c",
+			Output:    "build/main.o",
+		},
+		{
+			Directory: "/project",
+			Command:   "gcc -Iinc -DNDEBUG -Wall -c src/util.c",
+			File:      "/project/src/util.c",
+		},
+	}
+
+	tests := []struct {
+		format   string
+		fileName string
+		expected []string
+	}{
+		{
+			format:   "json",
+			fileName: "compile_commands.json",
+			expected: []string{`"file": "src/main.c"`, `"command": "gcc -Iinc -DNDEBUG -Wall -c src/util.c"`},
+		},
+		{
+			format:   "bear",
+			fileName: "compile_commands.json",
+			expected: []string{`"file": "/project/src/main.c"`, `"output": "/project/build/main.o"`, `"-DNDEBUG",`},
+		},
+		{
+			format:   "compile-flags",
+			fileName: "compile_flags.txt",
+			expected: []string{"-I/project/inc\n-Wall\n"},
+		},
+		{
+			format:   "csv",
+			fileName: "compile_commands.csv",
+			expected: []string{"directory,file,output,compiler,arguments\n", "/project,src/main.c,build/main.o,gcc,-Iinc -D 'MSG=hello world' -Wall -c src/main.c -o build/main.o\n"},
+		},
+		{
+			format:   "tsv",
+			fileName: "compile_commands.tsv",
+			expected: []string{"directory\tfile\toutput\tcompiler\targuments\n", "/project\t/project/src/util.c\t\tgcc\t-Iinc -DNDEBUG -Wall -c src/util.c\n"},
+		},
+		{
+			format:   "ninja",
+			fileName: "build.ninja",
+			expected: []string{"rule compile\n", "build /project/build/main.o: compile /project/src/main.c\n", "build /project/src/util.c.o: compile /project/src/util.c\n"},
+		},
+		{
+			format:   "cmake",
+			fileName: "yacd.cmake",
+			expected: []string{"add_library(yacd_sources OBJECT\n  \"/project/src/main.c\"\n", `INCLUDE_DIRECTORIES "/project/inc"`, `COMPILE_DEFINITIONS "MSG=hello world"`, `COMPILE_OPTIONS "-Wall"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			writer, err := NewDatabaseWriter(tt.format)
+			if err != nil {
+				t.Fatalf("NewDatabaseWriter(%s) error = %v", tt.format, err)
+			}
+			if writer.DefaultFileName() != tt.fileName {
+				t.Errorf("DefaultFileName() = %s, expected %s", writer.DefaultFileName(), tt.fileName)
+			}
+
+			var buf bytes.Buffer
+			if err := writer.Write(&buf, db); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(buf.String(), expected) {
+					t.Errorf("Write() output does not contain %q:\n%s", expected, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestNewDatabaseWriterUnknownFormat(t *testing.T) {
+	if _, err := NewDatabaseWriter("xml"); err == nil {
+		t.Error("NewDatabaseWriter(xml) expected an error")
+	}
+}
+
+func TestGroupFlags(t *testing.T) {
+	flags := []string{"-I", "inc", "-DX=1", "-D", "Y", "-Wall", "-include", "config.h"}
+	expected := [][]string{{"-I", "inc"}, {"-DX=1"}, {"-D", "Y"}, {"-Wall"}, {"-include", "config.h"}}
+
+	result := GroupFlags(flags)
+	if len(result) != len(expected) {
+		t.Fatalf("GroupFlags() = %v, expected %v", result, expected)
+	}
+	for i := range expected {
+		if strings.Join(result[i], " ") != strings.Join(expected[i], " ") {
+			t.Errorf("GroupFlags()[%d] = %v, expected %v", i, result[i], expected[i])
+		}
+	}
+}
+
+func TestCMakeList(t *testing.T) {
+	result := cmakeList([]string{`A="x;y"`, `C:\inc`, "$HOME"})
+	expected := `"A=\"x\;y\";C:\\inc;\$HOME"`
+	if result != expected {
+		t.Errorf("cmakeList() = %s, expected %s", result, expected)
+	}
+}
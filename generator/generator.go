@@ -1,37 +1,60 @@
 package generator
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
+	"github.com/gerryqd/yacd/parser"
 	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/diagutil"
 	"github.com/gerryqd/yacd/utils/errorutil"
 )
 
 // GenerateCompilationDatabase converts parsed make log entries to compilation database entries
-func GenerateCompilationDatabase(entries []types.MakeLogEntry, options *types.ParseOptions) ([]types.CompilationEntry, int) {
+func GenerateCompilationDatabase(entries []types.MakeLogEntry, options *types.ParseOptions) ([]types.CompilationEntry, int, error) {
 	var compilationDB []types.CompilationEntry
+	var translationUnits []types.CompilationEntry
 	missingFiles := 0
 
-	for i, entry := range entries {
-		// Convert to compilation entry
+	filter, err := newEntryFilter(options.Includes, options.Excludes)
+	if err != nil {
+		return nil, 0, err
+	}
+	flagRules, err := newFlagRules(options.FlagRules)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, entry := range entries {
+		// Rewrite path prefixes, canonicalise paths and drop filtered files
+		entry = applyPathMaps(entry, options.PathMaps)
+		entry = applyPathStyle(entry, options.PathStyle)
+		entry = canonicalizeEntry(entry, options.ResolveSymlinks)
+		if !filter.keep(entry) {
+			continue
+		}
+		entry = applyFlagRules(entry, flagRules)
+
+		// Convert to compilation entry; the arguments start with the compiler
 		compilationEntry := types.CompilationEntry{
 			Directory: entry.WorkingDir,
-			Command:   strings.Join(append([]string{entry.Compiler}, entry.Args...), " "),
+			Command:   ShellJoin(entry.Args),
 			File:      entry.SourceFile,
 			Output:    entry.OutputFile,
 		}
 
+		// Keep the untransformed entry for header inference
+		translationUnits = append(translationUnits, compilationEntry)
+
 		// Apply path transformations if needed
 		if options.UseRelativePaths {
 			compilationEntry = convertToRelativePaths(compilationEntry, options.BaseDir)
 		}
 
-		// Add to database
+		// Add to database; entries are numbered by their position in it
 		compilationDB = append(compilationDB, compilationEntry)
+		index := len(compilationDB)
 
 		// Check if source file exists
 		filePath := compilationEntry.File
@@ -47,18 +70,48 @@ func GenerateCompilationDatabase(entries []types.MakeLogEntry, options *types.Pa
 		// Check if file exists
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
 			missingFiles++
-			// Print warning message with "Warning:" in yellow and the rest in normal color
 			// Always print this warning, not just in verbose mode
-			fmt.Printf("\033[33mWarning:\033[0m source file does not exist: %s (entry %d)\n", compilationEntry.File, i+1)
+			diagutil.Print(diagutil.Warningf(index, "source file does not exist: %s", compilationEntry.File))
 		}
 
 		// Print verbose information if requested
 		if options.Verbose {
-			fmt.Printf("Entry %d: %s\n", i+1, compilationEntry.File)
+			if entry.DirectoryInferred {
+				fmt.Printf("Entry %d: %s (inferred directory %s)\n", index, compilationEntry.File, compilationEntry.Directory)
+			} else {
+				fmt.Printf("Entry %d: %s\n", index, compilationEntry.File)
+			}
+		}
+	}
+
+	if options.Verbose {
+		filter.printSummary()
+	}
+
+	// Add synthetic entries for headers
+	if options.InferHeaders {
+		for _, headerEntry := range InferHeaderEntries(translationUnits, options.HeaderIncludeScan) {
+			if options.UseRelativePaths {
+				headerEntry = convertToRelativePaths(headerEntry, options.BaseDir)
+			}
+			compilationDB = append(compilationDB, headerEntry)
+
+			if options.Verbose {
+				fmt.Printf("Entry %d: %s (inferred header)\n", len(compilationDB), headerEntry.File)
+			}
 		}
 	}
 
-	return compilationDB, missingFiles
+	return compilationDB, missingFiles, nil
+}
+
+// EntryArguments returns an entry's compiler arguments, splitting the command string
+// when the entry uses the "command" form
+func EntryArguments(entry types.CompilationEntry) []string {
+	if len(entry.Arguments) > 0 {
+		return entry.Arguments
+	}
+	return parser.SplitCommandLine(entry.Command)
 }
 
 // convertToRelativePaths converts absolute paths to relative paths based on baseDir
@@ -74,9 +127,23 @@ func convertToRelativePaths(entry types.CompilationEntry, baseDir string) types.
 	relativeEntry.File = getRelativePath(entry.File, baseDir)
 	relativeEntry.Output = getRelativePath(entry.Output, baseDir)
 
-	// Update command with relative paths
-	relativeEntry.Command = strings.ReplaceAll(entry.Command, entry.File, relativeEntry.File)
-	relativeEntry.Command = strings.ReplaceAll(entry.Command, entry.Output, relativeEntry.Output)
+	// Update command with relative paths; only whole source and output arguments are
+	// rewritten, so other arguments containing the same text are left alone
+	if entry.Command != "" {
+		args := EntryArguments(entry)
+		profile := parser.DetectArgsProfile(args)
+		relativeFile := replacePath(entry.File, relativeEntry.File)
+		relativeOutput := replacePath(entry.Output, relativeEntry.Output)
+		for i := 1; i < len(args); i++ {
+			if args[i] == entry.File {
+				args[i] = relativeEntry.File
+				continue
+			}
+			args[i] = canonicalOptionPath(args[i-1], args[i], profile.SourceOptions, relativeFile)
+			args[i] = canonicalOptionPath(args[i-1], args[i], profile.OutputOptions, relativeOutput)
+		}
+		relativeEntry.Command = ShellJoin(args)
+	}
 
 	return relativeEntry
 }
@@ -98,8 +165,14 @@ func getRelativePath(path, baseDir string) string {
 	return relPath
 }
 
-// WriteCompilationDatabase writes the compilation database to a JSON file
-func WriteCompilationDatabase(compilationDB []types.CompilationEntry, outputFile string) error {
+// WriteCompilationDatabase writes the compilation database to a file in the given
+// output format; an empty format writes a JSON compilation database
+func WriteCompilationDatabase(compilationDB []types.CompilationEntry, outputFile, format string) error {
+	writer, err := NewDatabaseWriter(format)
+	if err != nil {
+		return err
+	}
+
 	// Create or truncate the output file
 	file, err := os.Create(outputFile)
 	if err != nil {
@@ -107,23 +180,9 @@ func WriteCompilationDatabase(compilationDB []types.CompilationEntry, outputFile
 	}
 	defer file.Close()
 
-	// Marshal to JSON with indentation
-	data, err := json.MarshalIndent(compilationDB, "", "  ")
-	if err != nil {
-		return errorutil.WrapError(err, "failed to marshal compilation database to JSON")
-	}
-
-	// Write to file
-	_, err = file.Write(data)
-	if err != nil {
+	if err := writer.Write(file, compilationDB); err != nil {
 		return errorutil.WrapFileError(err, "write to", outputFile)
 	}
 
-	// Add newline at end of file
-	_, err = file.WriteString("\n")
-	if err != nil {
-		return errorutil.WrapFileError(err, "write newline to", outputFile)
-	}
-
 	return nil
 }
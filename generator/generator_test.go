@@ -2,9 +2,11 @@ package generator
 
 import (
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/gerryqd/yacd/types"
@@ -62,7 +64,10 @@ func TestGenerateCompilationDatabase(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, _ := GenerateCompilationDatabase(tt.entries, tt.options)
+			result, _, err := GenerateCompilationDatabase(tt.entries, tt.options)
+			if err != nil {
+				t.Fatalf("GenerateCompilationDatabase() unexpected error: %v", err)
+			}
 			if len(result) != tt.expected {
 				t.Errorf("GenerateCompilationDatabase() = %d entries, expected %d", len(result), tt.expected)
 			}
@@ -70,6 +75,110 @@ func TestGenerateCompilationDatabase(t *testing.T) {
 	}
 }
 
+// The baseline joined the compiler with arguments that already start with it, writing
+// "gcc gcc -c main.c", and did not quote arguments containing spaces
+func TestGenerateCompilationDatabaseCommand(t *testing.T) {
+	entries := []types.MakeLogEntry{{
+		WorkingDir: "/project",
+		Compiler:   "gcc",
+		Args:       []string{"gcc", "-DMSG=hello world", "-c", "main.c"},
+		SourceFile: "main.c",
+	}}
+
+	result, _, err := GenerateCompilationDatabase(entries, &types.ParseOptions{})
+	if err != nil {
+		t.Fatalf("GenerateCompilationDatabase() unexpected error: %v", err)
+	}
+
+	// The compiler appears once and arguments are shell-quoted
+	expected := "gcc '-DMSG=hello world' -c /project/main.c"
+	if len(result) != 1 || result[0].Command != expected {
+		t.Errorf("GenerateCompilationDatabase() command = %q, expected %q", result[0].Command, expected)
+	}
+}
+
+// Sources logged relative to the working directory must be found in the command by
+// the consumers that compare arguments with the "file" field
+func TestGenerateCompilationDatabaseRelativeSource(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"src/main.c": "#include \"main.h\"\n",
+		"src/main.h": "",
+	})
+
+	entries := []types.MakeLogEntry{{
+		WorkingDir: root,
+		Args:       []string{"gcc", "-Iinc", "-c", "src/main.c", "-o", "build/main.o"},
+		SourceFile: "src/main.c",
+		OutputFile: "build/main.o",
+	}}
+
+	db, _, err := GenerateCompilationDatabase(entries, &types.ParseOptions{InferHeaders: true})
+	if err != nil {
+		t.Fatalf("GenerateCompilationDatabase() unexpected error: %v", err)
+	}
+	if len(db) != 2 {
+		t.Fatalf("GenerateCompilationDatabase() = %d entries, expected a source and a header entry", len(db))
+	}
+
+	source := filepath.Join(root, "src", "main.c")
+	header := filepath.Join(root, "src", "main.h")
+	if db[0].File != source || !strings.Contains(db[0].Command, source) {
+		t.Errorf("Source entry = %+v, expected file and command to name %s", db[0], source)
+	}
+
+	// The header entry compiles the header instead of the translation unit
+	if db[1].File != header || !strings.Contains(db[1].Command, header) || strings.Contains(db[1].Command, "main.c") {
+		t.Errorf("Header entry command = %q, expected %s in place of the source", db[1].Command, header)
+	}
+
+	if flags := CompileFlags(EntryArguments(db[0]), db[0].File); strings.Join(flags, " ") != "-Iinc" {
+		t.Errorf("CompileFlags() = %v, expected [-Iinc]", flags)
+	}
+
+	for _, format := range []string{"compile-flags", "cmake"} {
+		writer, err := NewDatabaseWriter(format)
+		if err != nil {
+			t.Fatalf("NewDatabaseWriter(%s) unexpected error: %v", format, err)
+		}
+		var out strings.Builder
+		if err := writer.Write(&out, db[:1]); err != nil {
+			t.Fatalf("%s Write() unexpected error: %v", format, err)
+		}
+		if strings.Contains(strings.ReplaceAll(out.String(), source, ""), "main.c") {
+			t.Errorf("%s output lists the source file as a flag:\n%s", format, out.String())
+		}
+	}
+}
+
+// Warnings number entries by their position in the database, not in the log, which
+// differ once entries are filtered out
+func TestGenerateCompilationDatabaseEntryNumbers(t *testing.T) {
+	entries := []types.MakeLogEntry{
+		{WorkingDir: "/project", Args: []string{"gcc", "-c", "third_party/zlib.c"}, SourceFile: "third_party/zlib.c"},
+		{WorkingDir: "/project", Args: []string{"gcc", "-c", "missing.c"}, SourceFile: "missing.c"},
+	}
+	options := &types.ParseOptions{Excludes: []string{"third_party/**"}}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	_, missing, err := GenerateCompilationDatabase(entries, options)
+	os.Stdout = stdout
+	writer.Close()
+	output, _ := io.ReadAll(reader)
+
+	if err != nil || missing != 1 {
+		t.Fatalf("GenerateCompilationDatabase() = %d missing files, %v, expected 1 missing file", missing, err)
+	}
+	if !strings.Contains(string(output), "missing.c (entry 1)") {
+		t.Errorf("Output = %q, expected the missing file reported as entry 1", output)
+	}
+}
+
 func TestConvertToRelativePaths(t *testing.T) {
 	// Use platform-specific paths for testing
 	var baseDir string
@@ -149,6 +258,51 @@ func TestConvertToRelativePaths(t *testing.T) {
 	}
 }
 
+// The baseline rewrote the output path in the original command, losing the rewritten
+// source path, and replaced substrings, which also changed unrelated arguments
+func TestConvertToRelativePathsCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("expected paths use forward slashes")
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{
+			name:     "Source and output",
+			command:  "gcc -c /project/build/main.c -o /project/build/main.o",
+			expected: "gcc -c build/main.c -o build/main.o",
+		},
+		{
+			name:     "Attached output",
+			command:  "gcc -c /project/build/main.c -o/project/build/main.o",
+			expected: "gcc -c build/main.c -obuild/main.o",
+		},
+		{
+			name:     "Arguments containing the paths",
+			command:  "gcc -DSRC=/project/build/main.c -c /project/build/main.c -o /project/build/main.o -MF /project/build/main.o.d",
+			expected: "gcc -DSRC=/project/build/main.c -c build/main.c -o build/main.o -MF /project/build/main.o.d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := types.CompilationEntry{
+				Directory: "/project/build",
+				Command:   tt.command,
+				File:      "/project/build/main.c",
+				Output:    "/project/build/main.o",
+			}
+			result := convertToRelativePaths(entry, "/project")
+			if result.Command != tt.expected {
+				t.Errorf("Command = %s, expected %s", result.Command, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGetRelativePath(t *testing.T) {
 	// Use platform-specific paths for testing
 	var baseDir string
@@ -210,7 +364,7 @@ func TestWriteCompilationDatabase(t *testing.T) {
 		},
 	}
 
-	err := WriteCompilationDatabase(entries, tmpFile)
+	err := WriteCompilationDatabase(entries, tmpFile, "")
 	if err != nil {
 		t.Fatalf("WriteCompilationDatabase() error = %v", err)
 	}
@@ -0,0 +1,290 @@
+package generator
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gerryqd/yacd/parser"
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// includeDirectiveRegex matches #include "file" and #include <file>
+var includeDirectiveRegex = regexp.MustCompile(`^\s*#\s*include\s*([<"])([^>"]+)[>"]`)
+
+// EntryFilePath returns the absolute path of an entry's source file
+func EntryFilePath(entry types.CompilationEntry) string {
+	return pathutil.NormalizePath(pathutil.ResolveRelativePath(entry.Directory, entry.File))
+}
+
+// SourceArgument returns the argument naming an entry's source file, which may be
+// spelled differently from its "file" field, such as a relative argument for an
+// absolute file; the file itself is returned when no argument names it
+func SourceArgument(args []string, entry types.CompilationEntry) string {
+	file := EntryFilePath(entry)
+	for i := 1; i < len(args); i++ {
+		if args[i] == entry.File || pathutil.NormalizePath(pathutil.ResolveRelativePath(entry.Directory, args[i])) == file {
+			return args[i]
+		}
+	}
+	return entry.File
+}
+
+// IncludeDirectories returns the directories given with -I, -isystem, -iquote and -idirafter,
+// or with /I for MSVC-style compilers
+func IncludeDirectories(args []string) []string {
+	return includeDirectories(args, false)
+}
+
+// Include options naming system directories, whose headers do not belong to the project
+var systemIncludeOptions = map[string]bool{
+	"-isystem":    true,
+	"-idirafter":  true,
+	"/external:I": true,
+	"-external:I": true,
+	"-J":          true,
+}
+
+// includeDirectories returns the include directories of args, leaving out the system
+// include directories when projectOnly is set
+func includeDirectories(args []string, projectOnly bool) []string {
+	var dirs []string
+
+	for i := 0; i < len(args); i++ {
+		for _, option := range parser.DetectArgsProfile(args).IncludeOptions {
+			if !strings.HasPrefix(args[i], option) {
+				continue
+			}
+			var dir string
+			if args[i] == option {
+				if i+1 >= len(args) {
+					break
+				}
+				dir = args[i+1]
+				i++
+			} else {
+				dir = strings.TrimPrefix(args[i], option)
+			}
+			if !projectOnly || !systemIncludeOptions[option] {
+				dirs = append(dirs, dir)
+			}
+			break
+		}
+	}
+
+	return dirs
+}
+
+// ClosestEntry returns the index of the entry whose source file is nearest to path:
+// the deepest shared directory wins, then a matching file name stem, then the earlier entry.
+// Entries must share more than the filesystem root with path.
+func ClosestEntry(db []types.CompilationEntry, path string) (int, bool) {
+	best, bestScore := -1, 0
+	dir := filepath.Dir(path)
+	stem := fileStem(path)
+
+	for i, entry := range db {
+		entryFile := EntryFilePath(entry)
+
+		// Two points per shared directory level, one for a matching stem
+		score := 2 * pathutil.CommonPrefixDepth(dir, filepath.Dir(entryFile))
+		if fileStem(entryFile) == stem {
+			score++
+		}
+
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	// A shared root ("/" splits into one empty component) is not the same tree
+	if best < 0 || bestScore <= 3 {
+		return -1, false
+	}
+	return best, true
+}
+
+// HeaderEntry creates an entry for a header by borrowing a translation unit's command,
+// with the source file replaced by the header
+func HeaderEntry(tu types.CompilationEntry, header string) types.CompilationEntry {
+	args := append([]string(nil), EntryArguments(tu)...)
+	source := SourceArgument(args, tu)
+	for i, arg := range args {
+		if i > 0 && arg == source {
+			args[i] = header
+		}
+	}
+
+	entry := types.CompilationEntry{
+		Directory: tu.Directory,
+		File:      header,
+	}
+	if len(tu.Arguments) > 0 {
+		entry.Arguments = args
+	} else {
+		entry.Command = ShellJoin(args)
+	}
+	return entry
+}
+
+// InferHeaderEntries returns synthetic entries for headers that have no entry of their own,
+// similar to compdb's headerdb. By default headers are found in the -I directories and the
+// source directory of each translation unit and linked to the closest translation unit by
+// path. With includeScan, headers are found by following #include directives from each
+// source file instead and linked to the closest translation unit that includes them.
+func InferHeaderEntries(tus []types.CompilationEntry, includeScan bool) []types.CompilationEntry {
+	// Headers that already have entries are left alone
+	known := make(map[string]bool)
+	for _, tu := range tus {
+		known[EntryFilePath(tu)] = true
+	}
+
+	// Map each header to the translation units it is associated with
+	candidates := make(map[string][]int)
+	for i, tu := range tus {
+		var headers []string
+		if includeScan {
+			headers = includedHeaders(tu)
+		} else {
+			headers = nearbyHeaders(tu)
+		}
+		for _, header := range headers {
+			if !known[header] {
+				candidates[header] = append(candidates[header], i)
+			}
+		}
+	}
+
+	headers := make([]string, 0, len(candidates))
+	for header := range candidates {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+
+	var entries []types.CompilationEntry
+	for _, header := range headers {
+		// Pick the closest of the associated translation units
+		var related []types.CompilationEntry
+		for _, index := range candidates[header] {
+			related = append(related, tus[index])
+		}
+
+		tu := related[0]
+		if closest, ok := ClosestEntry(related, header); ok {
+			tu = related[closest]
+		}
+		entries = append(entries, HeaderEntry(tu, header))
+	}
+
+	return entries
+}
+
+// nearbyHeaders lists the headers in a translation unit's source directory and project
+// include directories; -isystem and -idirafter directories hold headers of other packages
+func nearbyHeaders(tu types.CompilationEntry) []string {
+	dirs := []string{filepath.Dir(EntryFilePath(tu))}
+	for _, dir := range includeDirectories(EntryArguments(tu), true) {
+		dirs = append(dirs, pathutil.NormalizePath(pathutil.ResolveRelativePath(tu.Directory, dir)))
+	}
+
+	var headers []string
+	for _, dir := range dirs {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if !file.IsDir() && pathutil.IsHeaderFile(file.Name()) {
+				headers = append(headers, filepath.Join(dir, file.Name()))
+			}
+		}
+	}
+
+	return headers
+}
+
+// includedHeaders follows #include directives from a translation unit's source file and
+// returns every header that resolves to a file on disk
+func includedHeaders(tu types.CompilationEntry) []string {
+	var includeDirs []string
+	for _, dir := range IncludeDirectories(EntryArguments(tu)) {
+		includeDirs = append(includeDirs, pathutil.NormalizePath(pathutil.ResolveRelativePath(tu.Directory, dir)))
+	}
+
+	var headers []string
+	visited := make(map[string]bool)
+	queue := []string{EntryFilePath(tu)}
+
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+
+		for _, include := range scanIncludes(file) {
+			header := resolveInclude(include, filepath.Dir(file), includeDirs)
+			if header == "" || visited[header] {
+				continue
+			}
+			visited[header] = true
+			headers = append(headers, header)
+			queue = append(queue, header)
+		}
+	}
+
+	return headers
+}
+
+// includeDirective is a single #include found in a file
+type includeDirective struct {
+	// Included path as written
+	path string
+
+	// Whether the include uses quotes rather than angle brackets
+	quoted bool
+}
+
+// scanIncludes returns the #include directives of a file
+func scanIncludes(path string) []includeDirective {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var includes []includeDirective
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if matches := includeDirectiveRegex.FindStringSubmatch(scanner.Text()); matches != nil {
+			includes = append(includes, includeDirective{path: matches[2], quoted: matches[1] == `"`})
+		}
+	}
+
+	return includes
+}
+
+// resolveInclude finds an included file like the preprocessor would: quoted includes are
+// looked up next to the including file first, then in the include directories
+func resolveInclude(include includeDirective, currentDir string, includeDirs []string) string {
+	dirs := includeDirs
+	if include.quoted {
+		dirs = append([]string{currentDir}, includeDirs...)
+	}
+
+	for _, dir := range dirs {
+		candidate := pathutil.JoinPaths(dir, include.path)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// fileStem returns the file name without directory and extension
+func fileStem(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
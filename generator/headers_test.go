@@ -0,0 +1,199 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+// writeTree creates files with the given contents below root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestIncludeDirectories(t *testing.T) {
+	args := []string{"gcc", "-Iinc", "-I", "../common", "-isystem", "/opt/sdk", "-iquote.", "-include", "config.h", "-c", "main.c"}
+	expected := []string{"inc", "../common", "/opt/sdk", "."}
+
+	result := IncludeDirectories(args)
+	if strings.Join(result, "|") != strings.Join(expected, "|") {
+		t.Errorf("IncludeDirectories() = %v, expected %v", result, expected)
+	}
+}
+
+func TestClosestEntry(t *testing.T) {
+	db := []types.CompilationEntry{
+		{Directory: "/project/app", File: "main.c"},
+		{Directory: "/project/lib", File: "list.c"},
+		{Directory: "/project/lib", File: "hash/hash.c"},
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedIndex int
+		expectedFound bool
+	}{
+		{"Same directory", "/project/app/config.h", 0, true},
+		{"Deepest shared directory", "/project/lib/hash/table.h", 2, true},
+		{"Matching stem breaks ties", "/project/lib/include/hash.h", 2, true},
+		{"Earlier entry breaks ties", "/project/lib/include/util.h", 1, true},
+		{"Outside the tree", "/usr/include/stdio.h", -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, found := ClosestEntry(db, tt.path)
+			if index != tt.expectedIndex || found != tt.expectedFound {
+				t.Errorf("ClosestEntry() = (%d, %v), expected (%d, %v)", index, found, tt.expectedIndex, tt.expectedFound)
+			}
+		})
+	}
+}
+
+func TestHeaderEntry(t *testing.T) {
+	commandTU := types.CompilationEntry{Directory: "/project", Command: "gcc -DX -c main.c -o main.o", File: "main.c", Output: "main.o"}
+	entry := HeaderEntry(commandTU, "/project/my header.h")
+	if entry.Command != "gcc -DX -c '/project/my header.h' -o main.o" || entry.File != "/project/my header.h" || entry.Output != "" {
+		t.Errorf("HeaderEntry() = %+v, expected command form with the header substituted", entry)
+	}
+
+	argumentsTU := types.CompilationEntry{Directory: "/project", Arguments: []string{"gcc", "-c", "main.c"}, File: "main.c"}
+	entry = HeaderEntry(argumentsTU, "/project/main.h")
+	if strings.Join(entry.Arguments, " ") != "gcc -c /project/main.h" || entry.Command != "" {
+		t.Errorf("HeaderEntry() = %+v, expected arguments form with the header substituted", entry)
+	}
+	if argumentsTU.Arguments[2] != "main.c" {
+		t.Errorf("HeaderEntry() modified the translation unit arguments: %v", argumentsTU.Arguments)
+	}
+
+	// Databases written by other tools may name the source differently from "file"
+	mixedTU := types.CompilationEntry{Directory: "/project/build", Command: "gcc -c ../src/main.c -o main.o", File: "/project/src/main.c"}
+	entry = HeaderEntry(mixedTU, "/project/src/main.h")
+	if entry.Command != "gcc -c /project/src/main.h -o main.o" {
+		t.Errorf("HeaderEntry() command = %q, expected the relative source argument replaced", entry.Command)
+	}
+	relativeTU := types.CompilationEntry{Directory: "/project", Arguments: []string{"gcc", "-c", "/project/src/main.c"}, File: "src/main.c"}
+	entry = HeaderEntry(relativeTU, "/project/src/main.h")
+	if strings.Join(entry.Arguments, " ") != "gcc -c /project/src/main.h" {
+		t.Errorf("HeaderEntry() arguments = %v, expected the absolute source argument replaced", entry.Arguments)
+	}
+}
+
+func TestSourceArgument(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    types.CompilationEntry
+		expected string
+	}{
+		{"Same spelling", types.CompilationEntry{Directory: "/project", Command: "gcc -c main.c", File: "main.c"}, "main.c"},
+		{"Relative argument", types.CompilationEntry{Directory: "/project/build", Command: "gcc -c ../src/./main.c", File: "/project/src/main.c"}, "../src/./main.c"},
+		{"Absolute argument", types.CompilationEntry{Directory: "/project", Command: "gcc -c /project/main.c", File: "main.c"}, "/project/main.c"},
+		{"No argument", types.CompilationEntry{Directory: "/project", Command: "gcc -c other.c", File: "main.c"}, "main.c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := SourceArgument(EntryArguments(tt.entry), tt.entry); result != tt.expected {
+				t.Errorf("SourceArgument() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInferHeaderEntries(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"app/main.c":          "#include \"app.h\"\n#include <lib/list.h>\nint main(void) { return 0; }\n",
+		"app/app.h":           "#include \"detail/impl.h\"\n",
+		"app/detail/impl.h":   "#include <stdio.h>\n",
+		"app/unused.h":        "",
+		"include/lib/list.h":  "#include \"app.h\"\n",
+		"include/lib/hash.h":  "",
+		"lib/list.c":          "#include <list.h>\n",
+		"lib/list.h":          "",
+		"lib/notes.txt":       "",
+		"include/lib/sub/x.h": "",
+		"sdk/include/sdk.h":   "",
+		"sdk/after/late.h":    "",
+	})
+
+	tus := []types.CompilationEntry{
+		// Headers in system include directories are not listed
+		{Directory: filepath.Join(root, "app"), Command: "gcc -I../include -isystem ../sdk/include -idirafter ../sdk/after -c main.c", File: "main.c"},
+		{Directory: filepath.Join(root, "lib"), Command: "gcc -I../include/lib -DLIB -c list.c", File: "list.c"},
+		// Headers that already have entries are not duplicated
+		{Directory: filepath.Join(root, "lib"), Command: "gcc -x c -c list.h", File: "list.h"},
+	}
+
+	tests := []struct {
+		name        string
+		includeScan bool
+		expected    map[string]string
+	}{
+		{
+			name:        "Include and source directories",
+			includeScan: false,
+			expected: map[string]string{
+				"app/app.h":          "app",
+				"app/unused.h":       "app",
+				"include/lib/hash.h": "lib",
+				"include/lib/list.h": "lib",
+			},
+		},
+		{
+			name:        "Include scan",
+			includeScan: true,
+			expected: map[string]string{
+				"app/app.h":          "app",
+				"app/detail/impl.h":  "app",
+				"include/lib/list.h": "lib",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := InferHeaderEntries(tus, tt.includeScan)
+
+			result := make(map[string]string)
+			var order []string
+			for _, entry := range entries {
+				rel, _ := filepath.Rel(root, entry.File)
+				dir, _ := filepath.Rel(root, entry.Directory)
+				result[filepath.ToSlash(rel)] = filepath.ToSlash(dir)
+				order = append(order, entry.File)
+
+				if !strings.Contains(entry.Command, entry.File) {
+					t.Errorf("Entry for %s has command %q without the header", rel, entry.Command)
+				}
+			}
+
+			if len(result) != len(tt.expected) {
+				t.Errorf("InferHeaderEntries() = %v, expected %v", result, tt.expected)
+			}
+			for header, dir := range tt.expected {
+				if result[header] != dir {
+					t.Errorf("Header %s linked to %q, expected %q", header, result[header], dir)
+				}
+			}
+			for i := 1; i < len(order); i++ {
+				if order[i-1] > order[i] {
+					t.Errorf("Entries are not sorted by path: %v", order)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/errorutil"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// flagRule is a compiled types.FlagRule
+type flagRule struct {
+	types.FlagRule
+
+	// Compiled Remove patterns
+	remove []*regexp.Regexp
+
+	// Compiled Replace pattern
+	replace *regexp.Regexp
+
+	// Compiled Files and Compiler scopes, nil when not given
+	files    *regexp.Regexp
+	compiler *regexp.Regexp
+}
+
+// newFlagRules compiles flag editing rules
+func newFlagRules(rules []types.FlagRule) ([]flagRule, error) {
+	compiled := make([]flagRule, len(rules))
+	for i, rule := range rules {
+		compiled[i].FlagRule = rule
+		for _, pattern := range rule.Remove {
+			compiled[i].remove = append(compiled[i].remove, pathutil.StringGlobRegexp(pattern))
+		}
+		if rule.Replace != "" {
+			regex, err := regexp.Compile(rule.Replace)
+			if err != nil {
+				return nil, errorutil.CreateInvalidArgumentError(rule.Replace, err.Error())
+			}
+			compiled[i].replace = regex
+		}
+		if rule.Files != "" {
+			compiled[i].files = pathutil.GlobRegexp(rule.Files)
+		}
+		if rule.Compiler != "" {
+			compiled[i].compiler = pathutil.GlobRegexp(rule.Compiler)
+		}
+	}
+	return compiled, nil
+}
+
+// applyFlagRules edits the arguments of an entry with every rule that applies to it
+func applyFlagRules(entry types.MakeLogEntry, rules []flagRule) types.MakeLogEntry {
+	if len(rules) == 0 || len(entry.Args) == 0 {
+		return entry
+	}
+
+	file := pathutil.NormalizePath(pathutil.ResolveRelativePath(entry.WorkingDir, entry.SourceFile))
+	args := append([]string(nil), entry.Args...)
+	for _, rule := range rules {
+		if rule.appliesTo(file, entry.Compiler) {
+			args = rule.apply(args)
+		}
+	}
+
+	entry.Args = args
+	return entry
+}
+
+// appliesTo reports whether the rule's file and compiler scopes match
+func (r flagRule) appliesTo(file, compiler string) bool {
+	if r.files != nil && !r.files.MatchString(pathutil.ToSlashPath(file)) {
+		return false
+	}
+	if r.compiler != nil && !r.compiler.MatchString(pathutil.ToSlashPath(compiler)) {
+		return false
+	}
+	return true
+}
+
+// apply edits args, keeping the compiler in front
+func (r flagRule) apply(args []string) []string {
+	compiler, flags := args[0], args[1:]
+
+	if len(r.remove) > 0 {
+		var kept []string
+		for _, arg := range flags {
+			if !matchesAny(r.remove, arg) {
+				kept = append(kept, arg)
+			}
+		}
+		flags = kept
+	}
+
+	for _, flag := range r.RemoveWithValue {
+		flags = removeFlagWithValue(flags, flag)
+	}
+
+	if r.replace != nil {
+		for i, arg := range flags {
+			flags[i] = r.replace.ReplaceAllString(arg, r.With)
+		}
+	}
+
+	edited := append([]string{compiler}, r.Prepend...)
+	edited = append(edited, flags...)
+	return append(edited, r.Append...)
+}
+
+// removeFlagWithValue removes a flag and its value, given either as the next
+// argument (-include config.h) or attached with "=" (--param=value)
+func removeFlagWithValue(args []string, flag string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == flag:
+			i++
+		case strings.HasPrefix(args[i], flag+"="):
+		default:
+			kept = append(kept, args[i])
+		}
+	}
+	return kept
+}
+
+// matchesAny reports whether any of the regular expressions matches value
+func matchesAny(regexes []*regexp.Regexp, value string) bool {
+	for _, regex := range regexes {
+		if regex.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestApplyFlagRules(t *testing.T) {
+	entry := types.MakeLogEntry{
+		WorkingDir: "/project",
+		Compiler:   "arm-none-eabi-gcc",
+		Args:       []string{"arm-none-eabi-gcc", "-Werror", "-flto", "-fprofile-arcs", "-include", "config.h", "--param=max-inline=10", "-std=gnu11", "-c", "src/main.c"},
+		SourceFile: "src/main.c",
+	}
+
+	tests := []struct {
+		name     string
+		rules    []types.FlagRule
+		expected string
+	}{
+		{
+			name:     "No rules",
+			expected: "arm-none-eabi-gcc -Werror -flto -fprofile-arcs -include config.h --param=max-inline=10 -std=gnu11 -c src/main.c",
+		},
+		{
+			name:     "Remove by glob",
+			rules:    []types.FlagRule{{Remove: []string{"-Werror", "-flto", "-fprofile-*"}}},
+			expected: "arm-none-eabi-gcc -include config.h --param=max-inline=10 -std=gnu11 -c src/main.c",
+		},
+		{
+			name:     "Remove by single-character glob",
+			rules:    []types.FlagRule{{Remove: []string{"-std=gnu??", "-fl?o", "-W?"}}},
+			expected: "arm-none-eabi-gcc -Werror -fprofile-arcs -include config.h --param=max-inline=10 -c src/main.c",
+		},
+		{
+			name:     "Remove with value",
+			rules:    []types.FlagRule{{RemoveWithValue: []string{"-include", "--param"}}},
+			expected: "arm-none-eabi-gcc -Werror -flto -fprofile-arcs -std=gnu11 -c src/main.c",
+		},
+		{
+			name:     "Prepend and append",
+			rules:    []types.FlagRule{{Prepend: []string{"--target=arm-none-eabi"}, Append: []string{"-D__clang_analyzer__"}}},
+			expected: "arm-none-eabi-gcc --target=arm-none-eabi -Werror -flto -fprofile-arcs -include config.h --param=max-inline=10 -std=gnu11 -c src/main.c -D__clang_analyzer__",
+		},
+		{
+			name:     "Regex replace",
+			rules:    []types.FlagRule{{Replace: `^-std=gnu(\d+)$`, With: "-std=c$1"}},
+			expected: "arm-none-eabi-gcc -Werror -flto -fprofile-arcs -include config.h --param=max-inline=10 -std=c11 -c src/main.c",
+		},
+		{
+			name:     "Rules apply in order",
+			rules:    []types.FlagRule{{Append: []string{"-Werror"}}, {Remove: []string{"-W*", "-f*"}}},
+			expected: "arm-none-eabi-gcc -include config.h --param=max-inline=10 -std=gnu11 -c src/main.c",
+		},
+		{
+			name:     "Compiler is never removed",
+			rules:    []types.FlagRule{{Remove: []string{"*"}}},
+			expected: "arm-none-eabi-gcc",
+		},
+		{
+			name: "Scoped by file and compiler",
+			rules: []types.FlagRule{
+				{Remove: []string{"-Werror"}, Files: "src/**"},
+				{Remove: []string{"-flto"}, Files: "third_party/**"},
+				{Remove: []string{"-std=*"}, Compiler: "arm-*-gcc"},
+				{Remove: []string{"-c"}, Compiler: "clang"},
+			},
+			expected: "arm-none-eabi-gcc -flto -fprofile-arcs -include config.h --param=max-inline=10 -c src/main.c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := newFlagRules(tt.rules)
+			if err != nil {
+				t.Fatalf("newFlagRules() unexpected error: %v", err)
+			}
+
+			result := applyFlagRules(entry, rules)
+			if strings.Join(result.Args, " ") != tt.expected {
+				t.Errorf("applyFlagRules() = %q, expected %q", strings.Join(result.Args, " "), tt.expected)
+			}
+		})
+	}
+
+	if entry.Args[1] != "-Werror" {
+		t.Errorf("applyFlagRules() modified the original arguments: %v", entry.Args)
+	}
+}
+
+func TestNewFlagRulesInvalidRegex(t *testing.T) {
+	if _, err := newFlagRules([]types.FlagRule{{Replace: "("}}); err == nil {
+		t.Error("newFlagRules() expected error for invalid regular expression, got nil")
+	}
+}
@@ -2,9 +2,13 @@ module github.com/gerryqd/yacd
 
 go 1.23
 
-require github.com/spf13/cobra v1.9.1
-
 require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.7 // indirect
-)
\ No newline at end of file
+	github.com/BurntSushi/toml v1.5.0
+	github.com/klauspost/compress v1.18.0
+	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.7
+	github.com/ulikunitz/xz v0.5.12
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -0,0 +1,67 @@
+package intercept
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Record is a compiler invocation seen by the intercept shim
+type Record struct {
+	// Arguments, starting with the path of the real compiler
+	Argv []string `json:"argv"`
+
+	// Directory the compiler ran in
+	Directory string `json:"cwd"`
+
+	// Environment as KEY=VAL strings
+	Environment []string `json:"env,omitempty"`
+}
+
+// AppendRecord appends a record as one JSON line. The line is written with a single
+// write to a file opened for appending, so records of parallel compilers do not interleave.
+func AppendRecord(path string, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadRecords reads JSON-lines records, skipping blank lines
+func ReadRecords(reader io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,51 @@
+package intercept
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAppendAndReadRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+
+	// Parallel compilers append to the same file
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record := Record{Argv: []string{"/usr/bin/gcc", "-c", "main.c"}, Directory: "/project", Environment: []string{"LANG=C"}}
+			if err := AppendRecord(path, record); err != nil {
+				t.Errorf("AppendRecord() unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open records: %v", err)
+	}
+	defer file.Close()
+
+	records, err := ReadRecords(file)
+	if err != nil {
+		t.Fatalf("ReadRecords() unexpected error: %v", err)
+	}
+	if len(records) != 20 {
+		t.Fatalf("ReadRecords() returned %d records, expected 20", len(records))
+	}
+	if records[0].Directory != "/project" || strings.Join(records[0].Argv, " ") != "/usr/bin/gcc -c main.c" || records[0].Environment[0] != "LANG=C" {
+		t.Errorf("ReadRecords()[0] = %+v", records[0])
+	}
+}
+
+func TestReadRecordsInvalid(t *testing.T) {
+	_, err := ReadRecords(strings.NewReader("{\"argv\":[\"gcc\"],\"cwd\":\"/\"}\n\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("ReadRecords() error = %v, expected an error on line 3", err)
+	}
+}
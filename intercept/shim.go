@@ -0,0 +1,204 @@
+package intercept
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gerryqd/yacd/parser"
+)
+
+// Environment variables passed from the intercept command to the shims
+const (
+	// RecordFileEnv names the JSON-lines file the shims append records to
+	RecordFileEnv = "YACD_INTERCEPT_RECORD"
+
+	// ShimDirEnv names the shim directory, which is skipped when looking up the real compiler
+	ShimDirEnv = "YACD_INTERCEPT_DIR"
+)
+
+// Compilers shimmed whether or not they are found in PATH
+var defaultCompilers = []string{"cc", "c++", "gcc", "g++", "clang", "clang++"}
+
+// GCC and Clang drivers found in PATH, including cross and versioned ones such as
+// arm-none-eabi-gcc and clang-17, but not tools such as gcc-ar
+var driverNameRegex = regexp.MustCompile(`^([\w.+]+-)*(cc|c\+\+|gcc|g\+\+|clang|clang\+\+)(-\d+(\.\d+)*)?$`)
+
+// IsShim reports whether the running process was started through a shim symlink
+func IsShim() bool {
+	if os.Getenv(RecordFileEnv) == "" || os.Getenv(ShimDirEnv) == "" || len(os.Args) == 0 {
+		return false
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	return filepath.Base(os.Args[0]) != filepath.Base(executable)
+}
+
+// RunShim records the compiler invocation in args, runs the real compiler with the
+// same arguments and returns its exit code
+func RunShim(args []string) int {
+	name := filepath.Base(args[0])
+	compiler, err := findCompiler(name, os.Getenv("PATH"), os.Getenv(ShimDirEnv))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "yacd intercept: %v\n", err)
+		return 127
+	}
+
+	directory, err := os.Getwd()
+	if err != nil {
+		directory = os.Getenv("PWD")
+	}
+	env := buildEnvironment(os.Environ(), os.Getenv(ShimDirEnv))
+	record := Record{
+		Argv:        append([]string{compiler}, args[1:]...),
+		Directory:   directory,
+		Environment: env,
+	}
+	if err := AppendRecord(os.Getenv(RecordFileEnv), record); err != nil {
+		fmt.Fprintf(os.Stderr, "yacd intercept: failed to record %s: %v\n", name, err)
+	}
+
+	// The compiler runs without the shims, so the tools a driver runs itself are not recorded
+	cmd := exec.Command(compiler, args[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "yacd intercept: %v\n", err)
+		return 127
+	}
+	return 0
+}
+
+// buildEnvironment returns the environment the build would have had without the shims
+func buildEnvironment(env []string, shimDir string) []string {
+	result := make([]string, 0, len(env))
+	for _, assignment := range env {
+		name, value, _ := strings.Cut(assignment, "=")
+		switch name {
+		case RecordFileEnv, ShimDirEnv:
+			continue
+		case "PATH":
+			var dirs []string
+			for _, dir := range filepath.SplitList(value) {
+				if dir != shimDir {
+					dirs = append(dirs, dir)
+				}
+			}
+			assignment = "PATH=" + strings.Join(dirs, string(os.PathListSeparator))
+		}
+		result = append(result, assignment)
+	}
+	return result
+}
+
+// findCompiler looks up an executable in PATH, skipping the shim directory
+func findCompiler(name, path, shimDir string) (string, error) {
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" || sameDirectory(dir, shimDir) {
+			continue
+		}
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in PATH outside the shim directory", name)
+}
+
+// sameDirectory reports whether two directory paths name the same directory
+func sameDirectory(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// CompilerNames returns the executable names to shim: the common drivers, the GCC and
+// Clang drivers and profile compilers found in PATH, and the extra names given
+func CompilerNames(path string, extra []string) []string {
+	names := make(map[string]bool)
+	for _, name := range defaultCompilers {
+		names[name] = true
+	}
+	for _, name := range extra {
+		names[name] = true
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if driverNameRegex.MatchString(name) || parser.DetectProfile(name) != parser.GCCProfile {
+				names[name] = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// CreateShimDir creates a temporary directory with a symlink to executable for every
+// compiler name. The caller removes the directory.
+func CreateShimDir(executable string, names []string) (string, error) {
+	dir, err := os.MkdirTemp("", "yacd-intercept-")
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range names {
+		if strings.ContainsAny(name, `/\`) {
+			continue
+		}
+		if err := os.Symlink(executable, filepath.Join(dir, name)); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// ShimEnvironment returns env with the shim directory first in PATH and the intercept
+// variables set
+func ShimEnvironment(env []string, shimDir, recordFile string) []string {
+	path := ""
+	result := make([]string, 0, len(env)+3)
+	for _, assignment := range env {
+		name, value, _ := strings.Cut(assignment, "=")
+		switch name {
+		case "PATH":
+			path = value
+		case RecordFileEnv, ShimDirEnv:
+		default:
+			result = append(result, assignment)
+		}
+	}
+
+	if path != "" {
+		path = shimDir + string(os.PathListSeparator) + path
+	} else {
+		path = shimDir
+	}
+	return append(result, "PATH="+path, RecordFileEnv+"="+recordFile, ShimDirEnv+"="+shimDir)
+}
@@ -0,0 +1,97 @@
+package intercept
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeExecutable creates an executable file
+func writeExecutable(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to create executable: %v", err)
+	}
+}
+
+func TestFindCompiler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("intercept is not supported on Windows")
+	}
+
+	shimDir := t.TempDir()
+	realDir := t.TempDir()
+	writeExecutable(t, filepath.Join(shimDir, "gcc"))
+	writeExecutable(t, filepath.Join(realDir, "gcc"))
+
+	path := strings.Join([]string{shimDir, realDir}, string(os.PathListSeparator))
+	compiler, err := findCompiler("gcc", path, shimDir)
+	if err != nil {
+		t.Fatalf("findCompiler() unexpected error: %v", err)
+	}
+	if compiler != filepath.Join(realDir, "gcc") {
+		t.Errorf("findCompiler() = %s, expected the compiler outside the shim directory", compiler)
+	}
+
+	if _, err := findCompiler("clang", path, shimDir); err == nil {
+		t.Error("findCompiler() expected error for a missing compiler")
+	}
+}
+
+func TestCompilerNames(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"arm-none-eabi-gcc", "clang-17", "x86_64-linux-gnu-g++-12", "gcc-ar", "iccarm", "armcc", "make"} {
+		writeExecutable(t, filepath.Join(dir, name))
+	}
+
+	names := strings.Join(CompilerNames(dir, []string{"xc8-cc"}), " ")
+	for _, expected := range []string{"arm-none-eabi-gcc", "clang-17", "x86_64-linux-gnu-g++-12", "iccarm", "armcc", "xc8-cc", "gcc", "cc"} {
+		if !strings.Contains(" "+names+" ", " "+expected+" ") {
+			t.Errorf("CompilerNames() = %s, expected %s", names, expected)
+		}
+	}
+	for _, unexpected := range []string{"gcc-ar", "make"} {
+		if strings.Contains(" "+names+" ", " "+unexpected+" ") {
+			t.Errorf("CompilerNames() = %s, did not expect %s", names, unexpected)
+		}
+	}
+}
+
+func TestCreateShimDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need extra privileges on Windows")
+	}
+
+	executable := filepath.Join(t.TempDir(), "yacd")
+	writeExecutable(t, executable)
+
+	dir, err := CreateShimDir(executable, []string{"gcc", "clang++"})
+	if err != nil {
+		t.Fatalf("CreateShimDir() unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"gcc", "clang++"} {
+		target, err := os.Readlink(filepath.Join(dir, name))
+		if err != nil || target != executable {
+			t.Errorf("Readlink(%s) = %s, %v, expected %s", name, target, err, executable)
+		}
+	}
+}
+
+func TestShimEnvironment(t *testing.T) {
+	env := []string{"HOME=/home/dev", "PATH=/usr/bin", RecordFileEnv + "=/old.jsonl"}
+	result := ShimEnvironment(env, "/tmp/shims", "/tmp/records.jsonl")
+
+	expected := []string{"HOME=/home/dev", "PATH=/tmp/shims" + string(os.PathListSeparator) + "/usr/bin", RecordFileEnv + "=/tmp/records.jsonl", ShimDirEnv + "=/tmp/shims"}
+	if strings.Join(result, "|") != strings.Join(expected, "|") {
+		t.Errorf("ShimEnvironment() = %v, expected %v", result, expected)
+	}
+
+	// The recorded environment is the one the build would have had
+	if original := buildEnvironment(result, "/tmp/shims"); strings.Join(original, "|") != "HOME=/home/dev|PATH=/usr/bin" {
+		t.Errorf("buildEnvironment() = %v", original)
+	}
+}
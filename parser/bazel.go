@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+// aqueryOutput is the part of "bazel aquery --output=jsonproto" output read here
+type aqueryOutput struct {
+	Actions []aqueryAction `json:"actions"`
+}
+
+// aqueryAction is an action of "bazel aquery --output=jsonproto" output
+type aqueryAction struct {
+	Mnemonic  string   `json:"mnemonic"`
+	Arguments []string `json:"arguments"`
+}
+
+// ParseAquery parses "bazel aquery" output in jsonproto or text form. Bazel prints
+// paths relative to its execution root, so entries run in the root directory.
+func (p *Parser) ParseAquery(reader io.Reader) ([]types.MakeLogEntry, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aquery output: %w", err)
+	}
+
+	var actions []aqueryAction
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		var output aqueryOutput
+		if err := json.Unmarshal(trimmed, &output); err != nil {
+			return nil, fmt.Errorf("failed to parse aquery jsonproto output: %w", err)
+		}
+		actions = output.Actions
+	} else {
+		actions, err = readAqueryTextActions(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []types.MakeLogEntry
+	for _, action := range actions {
+		if !isCompileMnemonic(action.Mnemonic) {
+			continue
+		}
+		if entry := p.ParseArgs(action.Arguments, p.currentWorkingDirectory()); entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+
+	if p.options.Verbose {
+		fmt.Printf("Found %d compile commands in %d aquery actions\n", len(entries), len(actions))
+	}
+	return entries, nil
+}
+
+// readAqueryTextActions reads the mnemonic and command line of each action in
+// "bazel aquery --output=text" output
+func readAqueryTextActions(reader io.Reader) ([]aqueryAction, error) {
+	var actions []aqueryAction
+	var command strings.Builder
+	inCommand := false
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if inCommand {
+			command.WriteByte(' ')
+			command.WriteString(strings.TrimSuffix(line, `\`))
+			if !strings.HasSuffix(line, `\`) {
+				inCommand = false
+				actions[len(actions)-1].Arguments = splitAqueryCommand(command.String())
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "action '"):
+			actions = append(actions, aqueryAction{})
+		case len(actions) == 0:
+		case strings.HasPrefix(line, "Mnemonic:"):
+			actions[len(actions)-1].Mnemonic = strings.TrimSpace(strings.TrimPrefix(line, "Mnemonic:"))
+		case strings.HasPrefix(line, "Command Line:"):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "Command Line:"))
+			command.Reset()
+			command.WriteString(strings.TrimSuffix(line, `\`))
+			if strings.HasSuffix(line, `\`) {
+				inCommand = true
+			} else {
+				actions[len(actions)-1].Arguments = splitAqueryCommand(command.String())
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read aquery output: %w", err)
+	}
+	return actions, nil
+}
+
+// splitAqueryCommand splits a text command line such as "(exec /usr/bin/gcc -c a.c)"
+func splitAqueryCommand(command string) []string {
+	command = strings.TrimSpace(command)
+	if strings.HasPrefix(command, "(") && strings.HasSuffix(command, ")") {
+		command = strings.TrimSpace(command[1 : len(command)-1])
+	}
+	command = strings.TrimPrefix(command, "exec ")
+	return SplitCommandLine(command)
+}
+
+// isCompileMnemonic reports whether an action mnemonic names a compile action, such as
+// CppCompile or ObjcCompile
+func isCompileMnemonic(mnemonic string) bool {
+	return strings.HasSuffix(mnemonic, "Compile")
+}
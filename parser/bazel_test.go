@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestParseAquery(t *testing.T) {
+	jsonproto := `{
+  "artifacts": [{"id": 1, "pathFragmentId": 2}],
+  "actions": [{
+    "targetId": 1,
+    "mnemonic": "CppCompile",
+    "arguments": ["/usr/bin/gcc", "-U_FORTIFY_SOURCE", "-iquote", ".", "-DNAME=\"app\"", "-c", "main/hello.cc", "-o", "bazel-out/k8-fastbuild/bin/main/_objs/hello/hello.o"]
+  }, {
+    "targetId": 1,
+    "mnemonic": "CppLink",
+    "arguments": ["/usr/bin/gcc", "-o", "bazel-out/k8-fastbuild/bin/main/hello", "bazel-out/k8-fastbuild/bin/main/_objs/hello/hello.o"]
+  }, {
+    "targetId": 2,
+    "mnemonic": "CppCompile",
+    "arguments": ["clang", "-c", "lib/util.c", "-o", "util.o"]
+  }]
+}`
+
+	text := `action 'Compiling main/hello.cc'
+  Mnemonic: CppCompile
+  Target: //main:hello
+  Configuration: k8-fastbuild
+  Environment: [PATH=/bin:/usr/bin, PWD=/proc/self/cwd]
+  Outputs: [bazel-out/k8-fastbuild/bin/main/_objs/hello/hello.o]
+  Command Line: (exec /usr/bin/gcc \
+    -U_FORTIFY_SOURCE \
+    -iquote \
+    . \
+    '-DNAME="app"' \
+    -c \
+    main/hello.cc \
+    -o \
+    bazel-out/k8-fastbuild/bin/main/_objs/hello/hello.o)
+# Configuration: 1c5a
+# Execution platform: @local_config_platform//:host
+
+action 'Linking main/hello'
+  Mnemonic: CppLink
+  Command Line: (exec /usr/bin/gcc -o bazel-out/k8-fastbuild/bin/main/hello bazel-out/k8-fastbuild/bin/main/_objs/hello/hello.o)
+
+action 'Compiling lib/util.c'
+  Mnemonic: CppCompile
+  Command Line: (exec clang -c lib/util.c -o util.o)
+`
+
+	for name, input := range map[string]string{"jsonproto": jsonproto, "text": text} {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewParser(types.ParseOptions{Directory: "/execroot/_main"})
+			if err != nil {
+				t.Fatalf("NewParser() unexpected error: %v", err)
+			}
+			entries, err := p.ParseAquery(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("ParseAquery() unexpected error: %v", err)
+			}
+			if len(entries) != 2 {
+				t.Fatalf("ParseAquery() returned %d entries, expected 2: %+v", len(entries), entries)
+			}
+
+			entry := entries[0]
+			if entry.WorkingDir != "/execroot/_main" || entry.SourceFile != "main/hello.cc" {
+				t.Errorf("entries[0] = %s in %s, expected main/hello.cc in /execroot/_main", entry.SourceFile, entry.WorkingDir)
+			}
+			if entry.OutputFile != "bazel-out/k8-fastbuild/bin/main/_objs/hello/hello.o" {
+				t.Errorf("entries[0].OutputFile = %s", entry.OutputFile)
+			}
+			expectedArgs := `/usr/bin/gcc -U_FORTIFY_SOURCE -iquote . -DNAME="app" -c main/hello.cc -o bazel-out/k8-fastbuild/bin/main/_objs/hello/hello.o`
+			if args := strings.Join(entry.Args, " "); args != expectedArgs {
+				t.Errorf("entries[0].Args = %s, expected %s", args, expectedArgs)
+			}
+			if entries[1].SourceFile != "lib/util.c" {
+				t.Errorf("entries[1].SourceFile = %s, expected lib/util.c", entries[1].SourceFile)
+			}
+		})
+	}
+
+	p, _ := NewParser(types.ParseOptions{})
+	if _, err := p.ParseAquery(strings.NewReader(`{"actions": [`)); err == nil {
+		t.Error("ParseAquery() with truncated jsonproto should fail")
+	}
+}
@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+const (
+	// Recursive make invocations as they appear in `make -n` output
+	makeInvocationPattern = `^(\S*/)?(g?make|\$\(MAKE\)|\$\{MAKE\})(\s|$)`
+)
+
+// makeInvocationRegex matches lines that run a (recursive) make
+var makeInvocationRegex = regexp.MustCompile(makeInvocationPattern)
+
+// makeValueOptions are the short make options taking a required value; -j and -l take
+// an optional value that can only be attached
+const makeValueOptions = "CEIWfo"
+
+// FindMakeDirectoryArgs returns the directories passed to make via -C/--directory, in order.
+// args are the make arguments without the make executable itself. Clustered short options
+// such as -kC dir are parsed the way make's getopt does, so the values of other options
+// are never mistaken for directories.
+func FindMakeDirectoryArgs(args []string) []string {
+	var dirs []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return dirs
+		case arg == "--directory":
+			if i+1 < len(args) {
+				dirs = append(dirs, args[i+1])
+				i++
+			}
+		case strings.HasPrefix(arg, "--directory="):
+			dirs = append(dirs, strings.TrimPrefix(arg, "--directory="))
+		case strings.HasPrefix(arg, "--") || !strings.HasPrefix(arg, "-") || arg == "-":
+			// Other long options, targets and variable assignments
+		default:
+			for j := 1; j < len(arg); j++ {
+				option := arg[j]
+				if option == 'j' || option == 'l' {
+					break
+				}
+				if !strings.ContainsRune(makeValueOptions, rune(option)) {
+					continue
+				}
+
+				// The rest of the cluster, or else the next argument, is the value
+				value := arg[j+1:]
+				if value == "" && i+1 < len(args) {
+					i++
+					value = args[i]
+				}
+				if option == 'C' && value != "" {
+					dirs = append(dirs, value)
+				}
+				break
+			}
+		}
+	}
+
+	return dirs
+}
+
+// handleMakeInvocation treats "make -C dir" and "$(MAKE) -C dir" lines as directory
+// pushes when make does not print Entering/Leaving messages
+func (p *Parser) handleMakeInvocation(line string) bool {
+	if !makeInvocationRegex.MatchString(line) {
+		return false
+	}
+
+	args := p.splitCommandLine(line)
+	dirs := FindMakeDirectoryArgs(args[1:])
+	if len(dirs) == 0 {
+		return false
+	}
+
+	// Without Leaving lines we cannot tell a nested sub-make from a sibling one.
+	// Prefer the deepest inferred directory the target exists under, otherwise
+	// resolve against the last directory make reported itself.
+	level := len(p.dirStack) - p.inferredDirs
+	for candidate := len(p.dirStack); candidate >= level && candidate > 0; candidate-- {
+		base := p.dirStack[candidate-1]
+		if dirExists(p.resolveMakeDirectories(base, dirs)) {
+			level = candidate
+			break
+		}
+	}
+
+	base := p.rootDir
+	if level > 0 {
+		base = p.dirStack[level-1]
+	}
+	dir := p.resolveMakeDirectories(base, dirs)
+
+	p.inferredDirs -= len(p.dirStack) - level
+	p.dirStack = append(p.dirStack[:level], dir)
+	p.inferredDirs++
+	p.directoryHistory = append(p.directoryHistory, dir)
+
+	if p.options.Verbose {
+		fmt.Printf("Inferred directory from make invocation: %s\n", dir)
+	}
+	return true
+}
+
+// resolveMakeDirectories applies successive -C options to a base directory
+func (p *Parser) resolveMakeDirectories(base string, dirs []string) string {
+	for _, dir := range dirs {
+		base = p.resolveRelativePath(base, dir)
+	}
+	return pathutil.NormalizePath(base)
+}
+
+// inferWorkingDirectory checks that a relative source exists under the entry's working
+// directory, and otherwise looks for it under the directories seen so far
+func (p *Parser) inferWorkingDirectory(entry *types.MakeLogEntry) {
+	if p.inferredDirs > 0 {
+		entry.DirectoryInferred = true
+	}
+
+	if pathutil.IsAbsolutePath(entry.SourceFile) ||
+		fileExists(pathutil.JoinPaths(entry.WorkingDir, entry.SourceFile)) {
+		return
+	}
+
+	// Most recently seen directories are the most likely candidates
+	candidates := append([]string{p.rootDir}, p.directoryHistory...)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if fileExists(pathutil.JoinPaths(candidates[i], entry.SourceFile)) {
+			if p.options.Verbose {
+				fmt.Printf("Inferred working directory %s for %s\n", candidates[i], entry.SourceFile)
+			}
+			entry.WorkingDir = candidates[i]
+			entry.DirectoryInferred = true
+			return
+		}
+	}
+}
+
+// fileExists reports whether path exists and is a regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// dirExists reports whether path exists and is a directory
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestFindMakeDirectoryArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "No directory",
+			args:     []string{"all"},
+			expected: nil,
+		},
+		{
+			name:     "Separate and attached forms",
+			args:     []string{"-C", "lib", "-Cdrivers", "all"},
+			expected: []string{"lib", "drivers"},
+		},
+		{
+			name:     "Long option forms",
+			args:     []string{"--directory=/src", "--directory", "app"},
+			expected: []string{"/src", "app"},
+		},
+		{
+			name:     "Clustered short options",
+			args:     []string{"-kC", "lib", "-sCdrivers", "-Bnk"},
+			expected: []string{"lib", "drivers"},
+		},
+		{
+			name:     "Values of other options",
+			args:     []string{"-f", "-Cfoo", "-ICbar", "-j4", "-C", "app"},
+			expected: []string{"app"},
+		},
+		{
+			name:     "Options end at double dash",
+			args:     []string{"-C", "lib", "--", "-Cdocs"},
+			expected: []string{"lib"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FindMakeDirectoryArgs(tt.args)
+			if strings.Join(result, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("FindMakeDirectoryArgs(%v) = %v, expected %v", tt.args, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseMakeLogInferDirectories(t *testing.T) {
+	// Build a source tree: root/app/main.c, root/lib/list.c, root/lib/hash/hash.c
+	root := t.TempDir()
+	for _, file := range []string{"app/main.c", "lib/list.c", "lib/hash/hash.c"} {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("int x;\n"), 0644); err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+	}
+
+	// Output of "make -s -n" without Entering/Leaving lines
+	makeLog := `make -C app all
+gcc -c main.c -o main.o
+$(MAKE) -C lib
+gcc -c list.c -o list.o
+make -C hash
+gcc -c hash.c -o hash.o
+gcc -c list.c -o list2.o`
+
+	options := types.ParseOptions{Directory: root, InferDirectories: true}
+	parser, err := NewParser(options)
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	entries, err := parser.ParseMakeLog(strings.NewReader(makeLog))
+	if err != nil {
+		t.Fatalf("ParseMakeLog() failed: %v", err)
+	}
+
+	expectedDirs := []string{
+		filepath.Join(root, "app"),
+		filepath.Join(root, "lib"),
+		filepath.Join(root, "lib", "hash"),
+		filepath.Join(root, "lib"), // resolved on disk from the directory history
+	}
+	if len(entries) != len(expectedDirs) {
+		t.Fatalf("Parsed %d entries, expected %d", len(entries), len(expectedDirs))
+	}
+
+	for i, expected := range expectedDirs {
+		if entries[i].WorkingDir != expected {
+			t.Errorf("Entry %d working directory = %s, expected %s", i, entries[i].WorkingDir, expected)
+		}
+		if !entries[i].DirectoryInferred {
+			t.Errorf("Entry %d should be flagged as having an inferred directory", i)
+		}
+	}
+}
+
+func TestParseMakeLogWithoutInference(t *testing.T) {
+	makeLog := `make -C app all
+gcc -c main.c -o main.o`
+
+	parser, err := NewParser(types.ParseOptions{Directory: "/project"})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	entries, err := parser.ParseMakeLog(strings.NewReader(makeLog))
+	if err != nil {
+		t.Fatalf("ParseMakeLog() failed: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Parsed %d entries, expected 1", len(entries))
+	}
+	if entries[0].WorkingDir != "/project" || entries[0].DirectoryInferred {
+		t.Errorf("Entry = %+v, expected /project without inference", entries[0])
+	}
+}
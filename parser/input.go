@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+// Input formats
+const (
+	// InputFormatAuto detects the format from the file name and contents
+	InputFormatAuto = "auto"
+
+	// InputFormatMake is a make log, also used for any log of plain command lines
+	InputFormatMake = "make"
+
+	// InputFormatNinja is the output of "ninja -t commands"
+	InputFormatNinja = "ninja"
+
+	// InputFormatNinjaLog is a .ninja_log, with commands read from the build.ninja beside it
+	InputFormatNinjaLog = "ninja-log"
+
+	// InputFormatAquery is the output of "bazel aquery", in jsonproto or text form
+	InputFormatAquery = "aquery"
+)
+
+// Number of bytes read to detect the input format
+const detectSize = 4096
+
+var (
+	// First line of a .ninja_log
+	ninjaLogHeaderRegex = regexp.MustCompile(`^# ninja log v\d+`)
+
+	// Action header of "bazel aquery --output=text"
+	aqueryTextRegex = regexp.MustCompile(`(?m)^action '`)
+)
+
+// InputFormats returns the names of the supported input formats
+func InputFormats() []string {
+	return []string{InputFormatAuto, InputFormatMake, InputFormatNinja, InputFormatNinjaLog, InputFormatAquery}
+}
+
+// IsValidInputFormat reports whether format names a supported input format
+func IsValidInputFormat(format string) bool {
+	for _, name := range InputFormats() {
+		if format == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectInputFormat picks the input format from the input name and its first bytes.
+// Logs of plain command lines, including "ninja -t commands" output, are make logs.
+func DetectInputFormat(name string, head []byte) string {
+	trimmed := bytes.TrimSpace(head)
+	switch {
+	case filepath.Base(name) == ".ninja_log" || ninjaLogHeaderRegex.Match(trimmed):
+		return InputFormatNinjaLog
+	case bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(trimmed, []byte(`"actions"`)) ||
+		bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(trimmed, []byte(`"artifacts"`)):
+		return InputFormatAquery
+	case aqueryTextRegex.Match(trimmed):
+		return InputFormatAquery
+	}
+	return InputFormatMake
+}
+
+// ParseInput parses an input in the given format, detecting it when the format is
+// empty or auto
+func (p *Parser) ParseInput(format string, reader io.Reader) ([]types.MakeLogEntry, error) {
+	if format == "" || format == InputFormatAuto {
+		buffered := bufio.NewReaderSize(reader, detectSize)
+		head, _ := buffered.Peek(detectSize)
+		format = DetectInputFormat(p.options.InputFile, head)
+		reader = buffered
+
+		if p.options.Verbose {
+			fmt.Printf("Detected input format: %s\n", format)
+		}
+	}
+
+	switch format {
+	case InputFormatMake, InputFormatNinja:
+		return p.ParseMakeLog(reader)
+	case InputFormatNinjaLog:
+		return p.ParseNinjaLog(reader, p.ninjaBuildDirectory())
+	case InputFormatAquery:
+		return p.ParseAquery(reader)
+	}
+	return nil, fmt.Errorf("unsupported input format %q, expected one of: %s", format, strings.Join(InputFormats(), ", "))
+}
+
+// ParseCommand parses a single command line run in the given directory
+func (p *Parser) ParseCommand(line, workingDir string) *types.MakeLogEntry {
+	p.dirStack = append(p.dirStack, workingDir)
+	defer func() { p.dirStack = p.dirStack[:len(p.dirStack)-1] }()
+
+	return p.parseCompileCommand(p.expandVariables(strings.TrimSpace(line)))
+}
+
+// ninjaBuildDirectory returns the directory of the build.ninja that belongs to the
+// .ninja_log being parsed: the log's own directory, or the root directory for stdin
+func (p *Parser) ninjaBuildDirectory() string {
+	if p.options.InputFile != "" && p.options.Directory == "" {
+		return filepath.Dir(absolutePath(p.options.InputFile))
+	}
+	return p.rootDir
+}
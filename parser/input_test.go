@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestDetectInputFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		head     string
+		expected string
+	}{
+		{"make log", "build.log", "make: Entering directory '/src'\ngcc -c main.c\n", InputFormatMake},
+		{"ninja commands", "commands.txt", "/usr/bin/cc -Iinclude -o main.o -c ../main.c\n", InputFormatMake},
+		{"ninja log by name", "build/.ninja_log", "", InputFormatNinjaLog},
+		{"ninja log by header", "", "# ninja log v5\n0\t10\t0\tmain.o\tabc\n", InputFormatNinjaLog},
+		{"aquery jsonproto", "", "{\n  \"artifacts\": [{\n    \"id\": 1\n", InputFormatAquery},
+		{"aquery jsonproto actions", "", `{"actions": []}`, InputFormatAquery},
+		{"aquery text", "", "action 'Compiling main.cc'\n  Mnemonic: CppCompile\n", InputFormatAquery},
+		{"json database is not aquery", "", `[{"file": "main.c"}]`, InputFormatMake},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := DetectInputFormat(tt.input, []byte(tt.head)); result != tt.expected {
+				t.Errorf("DetectInputFormat() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseInputFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		sources []string
+	}{
+		{
+			name:    "ninja commands",
+			format:  InputFormatNinja,
+			input:   "/usr/bin/cc -Iinclude -o CMakeFiles/app.dir/main.c.o -c /src/main.c\n/usr/bin/cc -o app CMakeFiles/app.dir/main.c.o\n",
+			sources: []string{"/src/main.c"},
+		},
+		{
+			name:    "detected aquery text",
+			format:  InputFormatAuto,
+			input:   "action 'Compiling lib.cc'\n  Mnemonic: CppCompile\n  Command Line: (exec /usr/bin/gcc -c lib.cc -o bazel-out/lib.o)\n",
+			sources: []string{"lib.cc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewParser(types.ParseOptions{Directory: "/build"})
+			if err != nil {
+				t.Fatalf("NewParser() unexpected error: %v", err)
+			}
+			entries, err := p.ParseInput(tt.format, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseInput() unexpected error: %v", err)
+			}
+			if len(entries) != len(tt.sources) {
+				t.Fatalf("ParseInput() returned %d entries, expected %d: %+v", len(entries), len(tt.sources), entries)
+			}
+			for i, source := range tt.sources {
+				if entries[i].SourceFile != source || entries[i].WorkingDir != "/build" {
+					t.Errorf("entries[%d] = %s in %s, expected %s in /build", i, entries[i].SourceFile, entries[i].WorkingDir, source)
+				}
+			}
+		})
+	}
+
+	p, _ := NewParser(types.ParseOptions{})
+	if _, err := p.ParseInput("scons", strings.NewReader("")); err == nil {
+		t.Error("ParseInput() with an unknown format should fail")
+	}
+}
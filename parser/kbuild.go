@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+var (
+	// Kbuild command files saved next to every object: .main.o.cmd
+	kbuildCmdFileRegex = regexp.MustCompile(`^\..+\.o\.cmd$`)
+
+	// Command line recorded in a kbuild command file (savedcmd_ since Linux 6.2, cmd_ before)
+	kbuildCmdLineRegex = regexp.MustCompile(`^(?:saved)?cmd_\S+\.o\s*:=\s*(.+)$`)
+)
+
+// FindKbuildCmdFiles returns the kbuild command files below a directory in lexical order
+func FindKbuildCmdFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && kbuildCmdFileRegex.MatchString(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// ParseKbuildDir parses the command files of a kbuild tree. Kbuild runs every compiler
+// from the top of the object tree, so entries use the given directory as working directory.
+func (p *Parser) ParseKbuildDir(root string) ([]types.MakeLogEntry, error) {
+	root = absolutePath(root)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	files, err := FindKbuildCmdFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []types.MakeLogEntry
+	for _, file := range files {
+		command, err := readKbuildCommand(file)
+		if err != nil {
+			return nil, err
+		}
+		if command == "" {
+			continue
+		}
+
+		entry := p.parseCompilerCommandOnly(command)
+		if entry == nil {
+			// Objects linked from other objects or generated without a compiler
+			continue
+		}
+		entry.WorkingDir = root
+		entries = append(entries, *entry)
+	}
+
+	if p.options.Verbose {
+		fmt.Printf("Parsed %d kbuild command files in %s\n", len(files), root)
+	}
+	return entries, nil
+}
+
+// readKbuildCommand returns the compile command recorded in a kbuild command file
+func readKbuildCommand(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if matches := kbuildCmdLineRegex.FindStringSubmatch(scanner.Text()); matches != nil {
+			return unescapeKbuildCommand(matches[1]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return "", nil
+}
+
+// unescapeKbuildCommand undoes the make escaping of a saved command and drops the
+// commands kbuild chains after the compiler (objtool, recordmcount)
+func unescapeKbuildCommand(command string) string {
+	command = strings.ReplaceAll(command, `\#`, "#")
+	command = strings.ReplaceAll(command, "$$", "$")
+	if index := strings.Index(command, "; "); index != -1 {
+		command = command[:index]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(command), ";"))
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestUnescapeKbuildCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"gcc -c -o init/main.o init/main.c", "gcc -c -o init/main.o init/main.c"},
+		{`gcc -DKBUILD_STR=\#s -c -o a.o a.c`, "gcc -DKBUILD_STR=#s -c -o a.o a.c"},
+		{"gcc -DVER=$$(cat v) -c a.c", "gcc -DVER=$(cat v) -c a.c"},
+		{"gcc -c -o a.o a.c  ; ./tools/objtool/objtool check a.o", "gcc -c -o a.o a.c"},
+		{"gcc -c -o a.o a.c;", "gcc -c -o a.o a.c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if result := unescapeKbuildCommand(tt.command); result != tt.expected {
+				t.Errorf("unescapeKbuildCommand() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseKbuildDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"init/.main.o.cmd": "savedcmd_init/main.o := gcc -Wp,-MMD,init/.main.o.d -nostdinc -I./include -DKBUILD_MODNAME='\"main\"' -c -o init/main.o init/main.c  ; ./tools/objtool/objtool --orc init/main.o\n\n" +
+			"source_init/main.o := init/main.c\n\ndeps_init/main.o := \\\n  include/linux/kconfig.h \\\n",
+		"arch/x86/kernel/.head_64.o.cmd": "cmd_arch/x86/kernel/head_64.o := gcc -D__ASSEMBLY__ -c -o arch/x86/kernel/head_64.o arch/x86/kernel/head_64.S\n",
+		"init/.built-in.a.cmd":           "savedcmd_init/built-in.a := rm -f init/built-in.a; ar cDPrST init/built-in.a init/main.o\n",
+		"kernel/.modules.o.cmd":          "savedcmd_kernel/modules.o := ld -m elf_x86_64 -r -o kernel/modules.o kernel/a.o\n",
+		".git/.stale.o.cmd":              "savedcmd_stale.o := gcc -c -o stale.o stale.c\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create command file: %v", err)
+		}
+	}
+
+	p, err := NewParser(types.ParseOptions{})
+	if err != nil {
+		t.Fatalf("NewParser() unexpected error: %v", err)
+	}
+	entries, err := p.ParseKbuildDir(root)
+	if err != nil {
+		t.Fatalf("ParseKbuildDir() unexpected error: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("ParseKbuildDir() returned %d entries, expected 2: %+v", len(entries), entries)
+	}
+	expected := []struct {
+		source string
+		output string
+		args   int
+	}{
+		{"arch/x86/kernel/head_64.S", "arch/x86/kernel/head_64.o", 6},
+		{"init/main.c", "init/main.o", 9},
+	}
+	for i, want := range expected {
+		entry := entries[i]
+		if entry.WorkingDir != root {
+			t.Errorf("entries[%d].WorkingDir = %s, expected %s", i, entry.WorkingDir, root)
+		}
+		if entry.SourceFile != want.source || entry.OutputFile != want.output {
+			t.Errorf("entries[%d] = %s -> %s, expected %s -> %s", i, entry.SourceFile, entry.OutputFile, want.source, want.output)
+		}
+		if len(entry.Args) != want.args {
+			t.Errorf("entries[%d].Args = %q, expected %d arguments", i, entry.Args, want.args)
+		}
+	}
+	if entries[1].Args[4] != `-DKBUILD_MODNAME="main"` {
+		t.Errorf("Define argument = %q, expected the shell quoting removed", entries[1].Args[4])
+	}
+
+	if _, err := p.ParseKbuildDir(filepath.Join(root, "missing")); err == nil {
+		t.Error("ParseKbuildDir() expected error for a missing directory")
+	}
+}
@@ -0,0 +1,401 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+// Limit on nested include and subninja statements
+const maxNinjaIncludeDepth = 32
+
+// ninjaManifest holds the parts of a build.ninja needed to recover build commands
+type ninjaManifest struct {
+	// Directory the manifest paths are relative to
+	dir string
+
+	// Top-level variables, already evaluated
+	vars map[string]string
+
+	// Unevaluated bindings of each rule
+	rules map[string]map[string]string
+
+	// Build statements by each of their outputs
+	builds map[string]*ninjaBuild
+}
+
+// ninjaBuild is a build statement
+type ninjaBuild struct {
+	rule    string
+	outputs []string
+	inputs  []string
+
+	// Build-level bindings, evaluated against the top-level variables
+	vars map[string]string
+}
+
+// ParseNinjaLog parses a .ninja_log and returns the compile commands of the outputs it
+// lists, taken from the build.ninja in buildDir. Entries run in buildDir.
+func (p *Parser) ParseNinjaLog(reader io.Reader, buildDir string) ([]types.MakeLogEntry, error) {
+	outputs, err := readNinjaLogOutputs(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := loadNinjaManifest(filepath.Join(buildDir, "build.ninja"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The build directory is known, so the root directory fallback does not apply
+	p.rootDirReported = true
+
+	var entries []types.MakeLogEntry
+	for _, output := range outputs {
+		build := manifest.builds[output]
+		if build == nil {
+			continue
+		}
+		command := manifest.command(build)
+		if command == "" {
+			continue
+		}
+		if entry := p.ParseCommand(command, manifest.dir); entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+
+	if p.options.Verbose {
+		fmt.Printf("Found %d compile commands for %d outputs in .ninja_log\n", len(entries), len(outputs))
+	}
+	return entries, nil
+}
+
+// readNinjaLogOutputs returns the outputs recorded in a .ninja_log in the order they were
+// last built, each once
+func readNinjaLogOutputs(reader io.Reader) ([]string, error) {
+	var logged []string
+	last := make(map[string]int)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// start, end, mtime, output, command hash
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 {
+			continue
+		}
+		last[fields[3]] = len(logged)
+		logged = append(logged, fields[3])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read .ninja_log: %w", err)
+	}
+
+	outputs := make([]string, 0, len(last))
+	for i, output := range logged {
+		if last[output] == i {
+			outputs = append(outputs, output)
+		}
+	}
+	return outputs, nil
+}
+
+// loadNinjaManifest reads a build.ninja and the files it includes
+func loadNinjaManifest(path string) (*ninjaManifest, error) {
+	manifest := &ninjaManifest{
+		dir:    filepath.Dir(absolutePath(path)),
+		vars:   make(map[string]string),
+		rules:  make(map[string]map[string]string),
+		builds: make(map[string]*ninjaBuild),
+	}
+	if err := manifest.load(path, 0); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// load parses one manifest file into the manifest
+func (m *ninjaManifest) load(path string, depth int) error {
+	if depth > maxNinjaIncludeDepth {
+		return fmt.Errorf("%s: include nested too deeply", path)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.dir, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lines, err := readNinjaLines(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// Bindings of the rule or build statement being read
+	var bindings map[string]string
+	var build *ninjaBuild
+	for _, line := range lines {
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if indented && bindings != nil {
+			if name, value, ok := splitNinjaBinding(line); ok {
+				if build != nil {
+					bindings[name] = m.evaluate(value, m.scope(build, nil))
+				} else {
+					bindings[name] = value
+				}
+			}
+			continue
+		}
+		bindings, build = nil, nil
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch keyword {
+		case "rule":
+			bindings = make(map[string]string)
+			m.rules[rest] = bindings
+		case "build":
+			build = m.parseBuild(rest)
+			if build != nil {
+				bindings = build.vars
+			}
+		case "include", "subninja":
+			if err := m.load(m.evaluate(rest, m.vars), depth+1); err != nil {
+				return err
+			}
+		case "pool":
+			// Pool bindings do not affect commands
+			bindings = make(map[string]string)
+		case "default":
+			// Default targets do not affect commands
+		default:
+			if name, value, ok := splitNinjaBinding(line); ok {
+				m.vars[name] = m.evaluate(value, m.vars)
+			}
+		}
+	}
+	return nil
+}
+
+// parseBuild parses the part of a build statement after "build"
+func (m *ninjaManifest) parseBuild(statement string) *ninjaBuild {
+	outputsPart, inputsPart, found := cutNinjaColon(statement)
+	if !found {
+		return nil
+	}
+
+	build := &ninjaBuild{vars: make(map[string]string)}
+
+	// Implicit outputs follow "|"
+	for _, output := range m.splitPaths(outputsPart) {
+		if output == "|" {
+			break
+		}
+		build.outputs = append(build.outputs, output)
+	}
+
+	inputs := m.splitPaths(inputsPart)
+	if len(inputs) == 0 {
+		return nil
+	}
+	build.rule = inputs[0]
+
+	// Implicit and order-only dependencies and validations follow "|", "||" and "|@"
+	for _, input := range inputs[1:] {
+		if strings.HasPrefix(input, "|") {
+			break
+		}
+		build.inputs = append(build.inputs, input)
+	}
+
+	for _, output := range build.outputs {
+		m.builds[output] = build
+	}
+	return build
+}
+
+// command returns the evaluated command of a build statement
+func (m *ninjaManifest) command(build *ninjaBuild) string {
+	rule := m.rules[build.rule]
+	if rule == nil {
+		return ""
+	}
+	return m.evaluate(rule["command"], m.scope(build, rule))
+}
+
+// scope returns the variables visible to a build statement. Build bindings override
+// rule bindings, which override the top-level variables; rule bindings are evaluated
+// against the build bindings, $in and $out.
+func (m *ninjaManifest) scope(build *ninjaBuild, rule map[string]string) map[string]string {
+	base := make(map[string]string, len(m.vars)+len(build.vars)+2)
+	for name, value := range m.vars {
+		base[name] = value
+	}
+	for name, value := range build.vars {
+		base[name] = value
+	}
+	base["in"] = joinNinjaPaths(build.inputs)
+	base["out"] = joinNinjaPaths(build.outputs)
+
+	scope := make(map[string]string, len(base)+len(rule))
+	for name, value := range base {
+		scope[name] = value
+	}
+	for name, value := range rule {
+		if _, ok := build.vars[name]; !ok && name != "command" {
+			scope[name] = m.evaluate(value, base)
+		}
+	}
+	return scope
+}
+
+// evaluate expands ninja variable references and escapes in a value
+func (m *ninjaManifest) evaluate(value string, scope map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		switch c := value[i]; {
+		case c == '$' || c == ' ' || c == ':':
+			b.WriteByte(c)
+		case c == '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end == -1 {
+				b.WriteString(value[i-1:])
+				return b.String()
+			}
+			b.WriteString(scope[value[i+1:i+end]])
+			i += end
+		case isNinjaVarChar(c):
+			start := i
+			for i < len(value) && isNinjaVarChar(value[i]) {
+				i++
+			}
+			b.WriteString(scope[value[start:i]])
+			i--
+		default:
+			b.WriteByte('$')
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// splitPaths splits a list of paths on unescaped spaces, evaluating each path against
+// the top-level variables
+func (m *ninjaManifest) splitPaths(list string) []string {
+	var paths []string
+	var current strings.Builder
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case c == '$' && i+1 < len(list):
+			// Keep the escape for evaluate, but never split on an escaped space
+			current.WriteByte(c)
+			i++
+			current.WriteByte(list[i])
+		case c == ' ' || c == '\t':
+			if current.Len() > 0 {
+				paths = append(paths, m.evaluate(current.String(), m.vars))
+				current.Reset()
+			}
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if current.Len() > 0 {
+		paths = append(paths, m.evaluate(current.String(), m.vars))
+	}
+	return paths
+}
+
+// readNinjaLines reads manifest lines, joining lines continued with a trailing "$"
+func readNinjaLines(reader io.Reader) ([]string, error) {
+	var lines []string
+	var continued strings.Builder
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if continued.Len() > 0 {
+			line = strings.TrimLeft(line, " \t")
+		}
+		if strings.HasSuffix(line, "$") && !strings.HasSuffix(line, "$$") {
+			continued.WriteString(strings.TrimSuffix(line, "$"))
+			continue
+		}
+		continued.WriteString(line)
+		lines = append(lines, continued.String())
+		continued.Reset()
+	}
+	if continued.Len() > 0 {
+		lines = append(lines, continued.String())
+	}
+
+	return lines, scanner.Err()
+}
+
+// splitNinjaBinding splits "name = value"
+func splitNinjaBinding(line string) (name, value string, ok bool) {
+	name, value, ok = strings.Cut(line, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t$") {
+		return "", "", false
+	}
+	return name, strings.TrimLeft(value, " \t"), true
+}
+
+// cutNinjaColon splits a build statement at its first unescaped colon
+func cutNinjaColon(statement string) (before, after string, found bool) {
+	for i := 0; i < len(statement); i++ {
+		switch statement[i] {
+		case '$':
+			i++
+		case ':':
+			return statement[:i], statement[i+1:], true
+		}
+	}
+	return statement, "", false
+}
+
+// joinNinjaPaths joins paths for $in and $out, quoting paths with spaces like ninja does
+func joinNinjaPaths(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		if strings.ContainsAny(path, " \t\"'") {
+			path = "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+		}
+		quoted[i] = path
+	}
+	return strings.Join(quoted, " ")
+}
+
+// isNinjaVarChar reports whether c may appear in a variable name written without braces
+func isNinjaVarChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
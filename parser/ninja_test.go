@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestReadNinjaLogOutputs(t *testing.T) {
+	log := "# ninja log v5\n" +
+		"0\t10\t100\tfoo.o\t1a\n" +
+		"0\t12\t100\tbar.o\t2b\n" +
+		"\n" +
+		"20\t30\t200\tfoo.o\t3c\n" +
+		"short line\n"
+
+	outputs, err := readNinjaLogOutputs(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("readNinjaLogOutputs() unexpected error: %v", err)
+	}
+	expected := []string{"bar.o", "foo.o"}
+	if strings.Join(outputs, " ") != strings.Join(expected, " ") {
+		t.Errorf("readNinjaLogOutputs() = %v, expected %v", outputs, expected)
+	}
+}
+
+func TestNinjaEvaluate(t *testing.T) {
+	m := &ninjaManifest{}
+	scope := map[string]string{"cc": "gcc", "in": "a.c", "out": "a.o", "my-flags": "-O2"}
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"$cc -c $in -o $out", "gcc -c a.c -o a.o"},
+		{"${cc} ${my-flags}", "gcc -O2"},
+		{"$my-flags.txt", "-O2.txt"},
+		{"echo $$HOME$:$ x", "echo $HOME: x"},
+		{"$undefined-c", ""},
+		{"trailing $", "trailing $"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if result := m.evaluate(tt.value, scope); result != tt.expected {
+				t.Errorf("evaluate() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseNinjaLog(t *testing.T) {
+	buildDir := t.TempDir()
+	files := map[string]string{
+		"build.ninja": "ninja_required_version = 1.5\n" +
+			"cflags = -Wall\n" +
+			"include rules.ninja\n" +
+			"\n" +
+			"build obj/main.o: cc ../src/main.c | gen/config.h || order\n" +
+			"  defines = -DAPP=1\n" +
+			"build obj/util.o | obj/util.d: cc ../src/util$ dir/util.c\n" +
+			"  cflags = $cflags -O2\n" +
+			"build app: link obj/main.o obj/util.o\n" +
+			"build obj/stale.o: cc ../src/stale.c\n" +
+			"default app\n",
+		"rules.ninja": "rule cc\n" +
+			"  depfile = $out.d\n" +
+			"  command = gcc $defines $cflags -MD -MF $depfile $\n" +
+			"      -c $in -o $out\n" +
+			"  description = CC $out\n" +
+			"rule link\n" +
+			"  command = gcc -o $out $in\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(buildDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	log := "# ninja log v5\n" +
+		"0\t10\t100\tobj/main.o\t1a\n" +
+		"0\t12\t100\tobj/util.o\t2b\n" +
+		"12\t20\t100\tapp\t3c\n" +
+		"0\t5\t100\tobj/removed.o\t4d\n"
+
+	p, err := NewParser(types.ParseOptions{})
+	if err != nil {
+		t.Fatalf("NewParser() unexpected error: %v", err)
+	}
+	entries, err := p.ParseNinjaLog(strings.NewReader(log), buildDir)
+	if err != nil {
+		t.Fatalf("ParseNinjaLog() unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		source string
+		args   string
+	}{
+		{"../src/main.c", "gcc -DAPP=1 -Wall -MD -MF obj/main.o.d -c ../src/main.c -o obj/main.o"},
+		{"../src/util dir/util.c", "gcc -Wall -O2 -MD -MF obj/util.o.d -c ../src/util dir/util.c -o obj/util.o"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("ParseNinjaLog() returned %d entries, expected %d: %+v", len(entries), len(expected), entries)
+	}
+	for i, want := range expected {
+		entry := entries[i]
+		if entry.WorkingDir != buildDir {
+			t.Errorf("entries[%d].WorkingDir = %s, expected %s", i, entry.WorkingDir, buildDir)
+		}
+		if entry.SourceFile != want.source {
+			t.Errorf("entries[%d].SourceFile = %s, expected %s", i, entry.SourceFile, want.source)
+		}
+		if args := strings.Join(entry.Args, " "); args != want.args {
+			t.Errorf("entries[%d].Args = %s, expected %s", i, args, want.args)
+		}
+	}
+
+	if _, err := p.ParseNinjaLog(strings.NewReader(log), t.TempDir()); err == nil {
+		t.Error("ParseNinjaLog() without build.ninja should fail")
+	}
+}
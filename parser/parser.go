@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/diagutil"
 	"github.com/gerryqd/yacd/utils/pathutil"
 )
 
@@ -28,6 +30,21 @@ type Parser struct {
 	// Directory history to track all directories we've seen
 	directoryHistory []string
 
+	// Root directory used when the log has no directory information
+	rootDir string
+
+	// Where rootDir came from (for diagnostics)
+	rootDirSource string
+
+	// Whether rootDir was chosen by fallback rather than given explicitly
+	rootDirIsFallback bool
+
+	// Whether the fallback root directory diagnostic has been printed
+	rootDirReported bool
+
+	// Number of inferred directories on top of the directory stack
+	inferredDirs int
+
 	// Compiler detection regular expression
 	compilerRegex *regexp.Regexp
 
@@ -37,13 +54,19 @@ type Parser struct {
 	// Make directory exit regular expression
 	makeDirLeaveRegex *regexp.Regexp
 
+	// Line pre-processing stages (ANSI codes, timestamps, CI prefixes)
+	lineStrippers []*regexp.Regexp
+
+	// Quiet build output seen, indexed like quietStyles
+	quietBuilds []QuietBuild
+
 	// Parse options
 	options types.ParseOptions
 }
 
 // NewParser creates a new parser
 func NewParser(options types.ParseOptions) (*Parser, error) {
-	compilerRegex, err := regexp.Compile(commonCompilers)
+	compilerRegex, err := regexp.Compile(compilerPattern(options.Compilers))
 	if err != nil {
 		return nil, fmt.Errorf("compiler regex compilation failed: %w", err)
 	}
@@ -58,14 +81,74 @@ func NewParser(options types.ParseOptions) (*Parser, error) {
 		return nil, fmt.Errorf("make directory exit regex compilation failed: %w", err)
 	}
 
-	return &Parser{
+	lineStrippers, err := newLineStrippers(options.StripPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &Parser{
 		dirStack:          make([]string, 0),
 		directoryHistory:  make([]string, 0),
 		compilerRegex:     compilerRegex,
 		makeDirEnterRegex: makeDirEnterRegex,
 		makeDirLeaveRegex: makeDirLeaveRegex,
+		lineStrippers:     lineStrippers,
 		options:           options,
-	}, nil
+	}
+	parser.rootDir, parser.rootDirSource, parser.rootDirIsFallback = resolveRootDirectory(options)
+
+	return parser, nil
+}
+
+// compilerPattern extends the common compiler pattern with the compiler profiles and
+// user-supplied executable names
+func compilerPattern(compilers []string) string {
+	pattern := strings.TrimSuffix(commonCompilers, ")") + "|" + profileCompilers()
+	for _, compiler := range compilers {
+		pattern += "|" + regexp.QuoteMeta(compiler)
+	}
+	return pattern + ")"
+}
+
+// isWrapper reports whether a word is a configured compiler launcher such as ccache
+func (p *Parser) isWrapper(word string) bool {
+	name := filepath.Base(word)
+	for _, wrapper := range p.options.Wrappers {
+		if name == wrapper || word == wrapper {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRootDirectory picks the directory for entries that appear before any
+// "Entering directory" line. The fallback chain is: explicit --directory, the make
+// -C root, an absolute base directory, the input log's directory, then the current
+// working directory.
+func resolveRootDirectory(options types.ParseOptions) (dir, source string, fallback bool) {
+	switch {
+	case options.Directory != "":
+		return absolutePath(options.Directory), "--directory", false
+	case options.RootDir != "":
+		return absolutePath(options.RootDir), "make root directory", false
+	case options.BaseDir != "" && pathutil.IsAbsolutePath(options.BaseDir):
+		return options.BaseDir, "base directory", false
+	case options.InputFile != "":
+		return absolutePath(pathutil.GetDirectoryFromPath(options.InputFile)), "input log directory", true
+	default:
+		return pathutil.GetWorkingDirectory(), "current working directory", true
+	}
+}
+
+// absolutePath makes a path absolute against the current working directory
+func absolutePath(path string) string {
+	if pathutil.IsAbsolutePath(path) {
+		return pathutil.NormalizePath(path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
 }
 
 // ParseMakeLog parses make log
@@ -73,13 +156,13 @@ func (p *Parser) ParseMakeLog(reader io.Reader) ([]types.MakeLogEntry, error) {
 	var entries []types.MakeLogEntry
 	scanner := bufio.NewScanner(reader)
 
-	// Set base directory
-	if p.options.BaseDir != "" {
-		p.dirStack = append(p.dirStack, p.options.BaseDir)
-	}
+	// Seed the directory stack with the root directory
+	p.dirStack = append(p.dirStack, p.rootDir)
 
+	lineNumber := 0
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		lineNumber++
+		line := p.preprocessLine(strings.TrimSpace(scanner.Text()))
 		if line == "" {
 			continue
 		}
@@ -94,9 +177,22 @@ func (p *Parser) ParseMakeLog(reader io.Reader) ([]types.MakeLogEntry, error) {
 			continue
 		}
 
-		// Parse compilation commands
-		if entry := p.parseCompileCommand(line); entry != nil {
+		// Follow "make -C dir" lines when make does not print directories
+		if p.options.InferDirectories && p.handleMakeInvocation(line) {
+			continue
+		}
+
+		// Parse compilation commands, expanding variables first since the compiler may be $(CC)
+		if entry := p.parseCompileCommand(p.expandVariables(line)); entry != nil {
+			if !p.dropUnresolvedReferences(entry, lineNumber) {
+				continue
+			}
+			if p.options.InferDirectories {
+				p.inferWorkingDirectory(entry)
+			}
 			entries = append(entries, *entry)
+		} else {
+			p.recordQuietLine(line, lineNumber)
 		}
 	}
 
@@ -111,7 +207,9 @@ func (p *Parser) ParseMakeLog(reader io.Reader) ([]types.MakeLogEntry, error) {
 func (p *Parser) handleDirectoryChange(line string) bool {
 	// Check if entering directory
 	if matches := p.makeDirEnterRegex.FindStringSubmatch(line); matches != nil {
-		dir := matches[2]
+		// Real directory information supersedes inferred directories
+		p.dropInferredDirectories()
+		dir := p.resolveRelativePath(p.currentWorkingDirectory(), matches[2])
 		p.dirStack = append(p.dirStack, dir)
 		// Also add to history
 		p.directoryHistory = append(p.directoryHistory, dir)
@@ -123,6 +221,7 @@ func (p *Parser) handleDirectoryChange(line string) bool {
 
 	// Check if leaving directory
 	if matches := p.makeDirLeaveRegex.FindStringSubmatch(line); matches != nil {
+		p.dropInferredDirectories()
 		if len(p.dirStack) > 0 {
 			if p.options.Verbose {
 				fmt.Printf("Leaving directory: %s\n", p.dirStack[len(p.dirStack)-1])
@@ -135,6 +234,12 @@ func (p *Parser) handleDirectoryChange(line string) bool {
 	return false
 }
 
+// dropInferredDirectories removes inferred directories from the top of the stack
+func (p *Parser) dropInferredDirectories() {
+	p.dirStack = p.dirStack[:len(p.dirStack)-p.inferredDirs]
+	p.inferredDirs = 0
+}
+
 // parseCompileCommand parses compilation commands
 func (p *Parser) parseCompileCommand(line string) *types.MakeLogEntry {
 	// Skip echo commands that might contain compiler names but are not actual compilation commands
@@ -144,6 +249,9 @@ func (p *Parser) parseCompileCommand(line string) *types.MakeLogEntry {
 
 	// Check for compiler command (simplified logic)
 	if p.compilerRegex.MatchString(line) {
+		// Look inside sh -c, env and similar launchers so that cd chains are found
+		line = p.unwrapCommand(line)
+
 		// Handle shell command chains with cd && compiler
 		if entry := p.parseShellCommandChain(line); entry != nil {
 			return entry
@@ -183,11 +291,7 @@ func (p *Parser) parseShellCommandChain(line string) *types.MakeLogEntry {
 	}
 
 	// Calculate the new working directory
-	currentWorkingDir := ""
-	if len(p.dirStack) > 0 {
-		currentWorkingDir = p.dirStack[len(p.dirStack)-1]
-	}
-	newWorkingDir := p.resolveRelativePath(currentWorkingDir, cdDir)
+	newWorkingDir := p.resolveRelativePath(p.currentWorkingDirectory(), cdDir)
 	entry.WorkingDir = newWorkingDir
 
 	// Don't modify source and output file paths here
@@ -203,8 +307,8 @@ func (p *Parser) parseShellCommandChain(line string) *types.MakeLogEntry {
 
 // parseCompilerCommandOnly parses a compiler command without directory inference
 func (p *Parser) parseCompilerCommandOnly(line string) *types.MakeLogEntry {
-	// Remove redirection operators before parsing
-	cleanLine := p.removeRedirectionOperators(line)
+	// Remove launchers and redirection operators before parsing
+	cleanLine := p.removeRedirectionOperators(p.unwrapCommand(line))
 
 	// Find the actual compiler in the command line
 	compilerStartIndex := p.findCompilerStartIndex(cleanLine)
@@ -221,6 +325,16 @@ func (p *Parser) parseCompilerCommandOnly(line string) *types.MakeLogEntry {
 		return nil
 	}
 
+	// The working directory will be set by the caller
+	return p.ParseArgs(args, "")
+}
+
+// ParseArgs parses a compiler invocation given as arguments, such as one recorded while
+// intercepting a build. It returns nil when the arguments compile no source file.
+func (p *Parser) ParseArgs(args []string, workingDir string) *types.MakeLogEntry {
+	if len(args) == 0 {
+		return nil
+	}
 	compiler := args[0]
 
 	// Find source file and output file
@@ -229,8 +343,11 @@ func (p *Parser) parseCompilerCommandOnly(line string) *types.MakeLogEntry {
 		return nil
 	}
 
+	// Record options of other compiler families in their clang form
+	args = DetectArgsProfile(args).Translate(args)
+
 	return &types.MakeLogEntry{
-		WorkingDir: "", // Will be set by caller
+		WorkingDir: workingDir,
 		Compiler:   compiler,
 		Args:       args,
 		SourceFile: sourceFile,
@@ -245,6 +362,9 @@ func (p *Parser) findCompilerStartIndex(line string) int {
 
 	// Look for the compiler pattern in the words
 	for i, word := range words {
+		if p.isWrapper(word) {
+			continue
+		}
 		if p.compilerRegex.MatchString(word) {
 			// Found a compiler, calculate its position in the original line
 			// Reconstruct the prefix to find the exact position
@@ -264,6 +384,20 @@ func (p *Parser) findCompilerStartIndex(line string) int {
 	return -1
 }
 
+// currentWorkingDirectory returns the directory on top of the stack, falling back
+// to the root directory so that entries never get an empty directory
+func (p *Parser) currentWorkingDirectory() string {
+	if n := len(p.dirStack); n > 1 || (n == 1 && p.dirStack[0] != p.rootDir) {
+		return p.dirStack[n-1]
+	}
+
+	if p.rootDirIsFallback && !p.rootDirReported {
+		p.rootDirReported = true
+		diagutil.Print(diagutil.Warningf(0, "no directory information in log, using %s: %s", p.rootDirSource, p.rootDir))
+	}
+	return p.rootDir
+}
+
 // resolveRelativePath resolves a relative path against a base directory
 func (p *Parser) resolveRelativePath(baseDir, relativePath string) string {
 	return pathutil.ResolveRelativePath(baseDir, relativePath)
@@ -271,76 +405,59 @@ func (p *Parser) resolveRelativePath(baseDir, relativePath string) string {
 
 // parseDirectCompileCommand parses direct compilation commands
 func (p *Parser) parseDirectCompileCommand(line string) *types.MakeLogEntry {
-	// Remove redirection operators before parsing
-	cleanLine := p.removeRedirectionOperators(line)
-
-	// Find the actual compiler in the command line
-	compilerStartIndex := p.findCompilerStartIndex(cleanLine)
-	if compilerStartIndex == -1 {
-		return nil
-	}
-
-	// Extract the compiler command part
-	compilerCommand := cleanLine[compilerStartIndex:]
-
-	// Split command line arguments
-	args := p.splitCommandLine(compilerCommand)
-	if len(args) == 0 {
-		return nil
-	}
-
-	compiler := args[0]
-
-	// Find source file and output file
-	sourceFile, outputFile := p.extractFiles(args)
-	if sourceFile == "" {
+	entry := p.parseCompilerCommandOnly(line)
+	if entry == nil {
 		return nil
 	}
 
-	// Get current working directory from stack
-	workingDir := ""
-	if len(p.dirStack) > 0 {
-		workingDir = p.dirStack[len(p.dirStack)-1]
-	}
-
-	return &types.MakeLogEntry{
-		WorkingDir: workingDir,
-		Compiler:   compiler,
-		Args:       args,
-		SourceFile: sourceFile,
-		OutputFile: outputFile,
-	}
+	entry.WorkingDir = p.currentWorkingDirectory()
+	return entry
 }
 
-// splitCommandLine splits command line, handling quotes and escape characters
+// splitCommandLine splits a command line starting with the compiler, using the
+// tokenisation of its compiler profile
 func (p *Parser) splitCommandLine(line string) []string {
+	return profileForLine(line).Split(line)
+}
+
+// SplitCommandLine splits a shell command line into arguments, handling quotes and escape characters
+func SplitCommandLine(line string) []string {
 	var args []string
 	var current strings.Builder
-	inQuotes := false
+	var quote rune
 	escaped := false
 
-	for i, char := range line {
+	// An argument starts with any character but a separating space, so that quoted
+	// empty arguments ('') are kept
+	started := false
+
+	for _, char := range line {
 		switch {
 		case escaped:
 			current.WriteRune(char)
 			escaped = false
-		case char == '\\':
+		case char == '\\' && quote != '\'':
 			escaped = true
-		case char == '"' || char == '\'':
-			inQuotes = !inQuotes
-		case char == ' ' && !inQuotes:
-			if current.Len() > 0 {
+			started = true
+		case quote == 0 && (char == '"' || char == '\''):
+			quote = char
+			started = true
+		case char == quote:
+			quote = 0
+		case char == ' ' && quote == 0:
+			if started {
 				args = append(args, current.String())
 				current.Reset()
+				started = false
 			}
 		default:
 			current.WriteRune(char)
+			started = true
 		}
+	}
 
-		// Handle end of line case
-		if i == len(line)-1 && current.Len() > 0 {
-			args = append(args, current.String())
-		}
+	if started {
+		args = append(args, current.String())
 	}
 
 	return args
@@ -348,20 +465,7 @@ func (p *Parser) splitCommandLine(line string) []string {
 
 // extractFiles extracts source file and output file from arguments
 func (p *Parser) extractFiles(args []string) (sourceFile, outputFile string) {
-	for i, arg := range args {
-		// Find output file (-o parameter)
-		if arg == "-o" && i+1 < len(args) {
-			outputFile = args[i+1]
-			continue
-		}
-
-		// Find source file (usually the last .c or .cpp file)
-		if p.isSourceFile(arg) {
-			sourceFile = arg
-		}
-	}
-
-	return sourceFile, outputFile
+	return DetectArgsProfile(args).ExtractFiles(args)
 }
 
 // isSourceFile determines if it is a source file
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -151,6 +154,11 @@ func TestSplitCommandLine(t *testing.T) {
 			input:    "gcc -DMESSAGE='Hello World' -c main.c",
 			expected: []string{"gcc", "-DMESSAGE=Hello World", "-c", "main.c"},
 		},
+		{
+			name:     "Command with nested quotes",
+			input:    "gcc '-DMESSAGE=\"Hello World\"' -DPATH='C:\\dir' -c main.c",
+			expected: []string{"gcc", "-DMESSAGE=\"Hello World\"", "-DPATH=C:\\dir", "-c", "main.c"},
+		},
 		{
 			name:     "Command with escape characters",
 			input:    "gcc -DMESSAGE=\\\"Hello\\\" -c main.c",
@@ -182,6 +190,66 @@ func TestSplitCommandLine(t *testing.T) {
 	}
 }
 
+// Quotes of the other kind and backslashes are literal inside single quotes, as in a
+// POSIX shell; commands written with generator.ShellJoin depend on it
+func TestSplitCommandLineQuotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Double quotes inside single quotes",
+			input:    `gcc '-DMSG="hello world"' -c main.c`,
+			expected: []string{"gcc", `-DMSG="hello world"`, "-c", "main.c"},
+		},
+		{
+			name:     "Single quotes inside double quotes",
+			input:    `gcc "-DCHAR='x' y" -c main.c`,
+			expected: []string{"gcc", "-DCHAR='x' y", "-c", "main.c"},
+		},
+		{
+			name:     "Backslashes inside single quotes",
+			input:    `cl /c 'C:\work\src dir\main.c'`,
+			expected: []string{"cl", "/c", `C:\work\src dir\main.c`},
+		},
+		{
+			name:     "Escaped quote inside double quotes",
+			input:    `gcc "-DMSG=\"hi there\"" -c main.c`,
+			expected: []string{"gcc", `-DMSG="hi there"`, "-c", "main.c"},
+		},
+		{
+			name:     "Empty quoted arguments",
+			input:    `gcc '' -DEMPTY="" -c "" main.c`,
+			expected: []string{"gcc", "", "-DEMPTY=", "-c", "", "main.c"},
+		},
+		{
+			name:     "Last argument ending in a multibyte character",
+			input:    "gcc -c src/é",
+			expected: []string{"gcc", "-c", "src/é"},
+		},
+		{
+			name:     "Trailing spaces",
+			input:    "gcc -c main.c  ",
+			expected: []string{"gcc", "-c", "main.c"},
+		},
+		{
+			name:     "Shell-quoted single quote",
+			input:    `gcc '-DNAME="it'\''s"' -c main.c`,
+			expected: []string{"gcc", `-DNAME="it's"`, "-c", "main.c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SplitCommandLine(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("SplitCommandLine() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestExtractFiles(t *testing.T) {
 	options := types.ParseOptions{}
 	parser, err := NewParser(options)
@@ -418,6 +486,118 @@ make: Leaving directory '/home/user/project'`
 	}
 }
 
+func TestParseMakeLogWithRootDir(t *testing.T) {
+	options := types.ParseOptions{RootDir: "/home/user/project", BaseDir: "/project"}
+	parser, err := NewParser(options)
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	// Entries before any "Entering directory" line belong to the root directory
+	makeLog := `gcc -c main.c -o main.o
+cd drivers && gcc -c uart.c -o uart.o
+make: Entering directory '/home/user/project/lib'
+gcc -c list.c -o list.o
+make: Leaving directory '/home/user/project/lib'`
+
+	entries, err := parser.ParseMakeLog(strings.NewReader(makeLog))
+	if err != nil {
+		t.Fatalf("ParseMakeLog() failed: %v", err)
+	}
+
+	expectedDirs := []string{"/home/user/project", "/home/user/project/drivers", "/home/user/project/lib"}
+	if len(entries) != len(expectedDirs) {
+		t.Fatalf("Parsed %d entries, expected %d", len(entries), len(expectedDirs))
+	}
+
+	for i, expected := range expectedDirs {
+		if entries[i].WorkingDir != expected {
+			t.Errorf("Entry %d working directory = %s, expected %s", i, entries[i].WorkingDir, expected)
+		}
+	}
+}
+
+func TestResolveRootDirectory(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		options          types.ParseOptions
+		expectedDir      string
+		expectedFallback bool
+	}{
+		{
+			name:        "Explicit directory wins",
+			options:     types.ParseOptions{Directory: "/explicit", RootDir: "/make/root", InputFile: "/logs/build.log"},
+			expectedDir: "/explicit",
+		},
+		{
+			name:        "Make root directory",
+			options:     types.ParseOptions{RootDir: "/make/root", InputFile: "/logs/build.log"},
+			expectedDir: "/make/root",
+		},
+		{
+			name:        "Absolute base directory",
+			options:     types.ParseOptions{BaseDir: "/project", InputFile: "/logs/build.log"},
+			expectedDir: "/project",
+		},
+		{
+			name:             "Input log directory",
+			options:          types.ParseOptions{BaseDir: "build", InputFile: "/logs/build.log"},
+			expectedDir:      "/logs",
+			expectedFallback: true,
+		},
+		{
+			name:             "Current working directory",
+			options:          types.ParseOptions{},
+			expectedDir:      wd,
+			expectedFallback: true,
+		},
+		{
+			name:        "Relative explicit directory is made absolute",
+			options:     types.ParseOptions{Directory: "src"},
+			expectedDir: filepath.Join(wd, "src"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, _, fallback := resolveRootDirectory(tt.options)
+			if dir != tt.expectedDir {
+				t.Errorf("resolveRootDirectory() dir = %s, expected %s", dir, tt.expectedDir)
+			}
+			if fallback != tt.expectedFallback {
+				t.Errorf("resolveRootDirectory() fallback = %v, expected %v", fallback, tt.expectedFallback)
+			}
+		})
+	}
+}
+
+func TestParseMakeLogNeverEmitsEmptyDirectory(t *testing.T) {
+	makeLog := `gcc -c main.c -o main.o
+cd drivers && gcc -c uart.c -o uart.o`
+
+	entries, err := ParseMakeLog(strings.NewReader(makeLog), false)
+	if err != nil {
+		t.Fatalf("ParseMakeLog() failed: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Parsed %d entries, expected 2", len(entries))
+	}
+
+	wd, _ := os.Getwd()
+	if entries[0].WorkingDir != wd {
+		t.Errorf("First entry working directory = %s, expected %s", entries[0].WorkingDir, wd)
+	}
+	if entries[1].WorkingDir != filepath.Join(wd, "drivers") {
+		t.Errorf("Second entry working directory = %s, expected %s", entries[1].WorkingDir, filepath.Join(wd, "drivers"))
+	}
+}
+
 func TestParseMakeLogWithComments(t *testing.T) {
 	options := types.ParseOptions{BaseDir: "/project"}
 	parser, err := NewParser(options)
@@ -937,3 +1117,65 @@ func TestParseCompileCommandWithPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCompileCommandWithCompilersAndWrappers(t *testing.T) {
+	options := types.ParseOptions{
+		Directory: "/project",
+		Compilers: []string{"xc8", "c51"},
+		Wrappers:  []string{"ccache", "distcc"},
+	}
+	parser, err := NewParser(options)
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		line             string
+		expectedCompiler string
+		expectedArgs     string
+	}{
+		{
+			name:             "Configured compiler",
+			line:             "xc8 --chip=16F877A -c main.c -o main.p1",
+			expectedCompiler: "xc8",
+			expectedArgs:     "xc8 --chip=16F877A -c main.c -o main.p1",
+		},
+		{
+			name:             "Wrapper before the compiler",
+			line:             "ccache gcc -c main.c -o main.o",
+			expectedCompiler: "gcc",
+			expectedArgs:     "gcc -c main.c -o main.o",
+		},
+		{
+			name:             "Wrapper given by path",
+			line:             "/usr/bin/ccache distcc c51 -c main.c -o main.o",
+			expectedCompiler: "c51",
+			expectedArgs:     "c51 -c main.c -o main.o",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parser.parseCompileCommand(tt.line)
+			if result == nil {
+				t.Fatal("parseCompileCommand() = nil, expected non-nil")
+			}
+			if result.Compiler != tt.expectedCompiler {
+				t.Errorf("Compiler = %s, expected %s", result.Compiler, tt.expectedCompiler)
+			}
+			if strings.Join(result.Args, " ") != tt.expectedArgs {
+				t.Errorf("Args = %v, expected %s", result.Args, tt.expectedArgs)
+			}
+		})
+	}
+
+	// Without configuration the unknown compiler is not recognised
+	defaultParser, err := NewParser(types.ParseOptions{Directory: "/project"})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+	if result := defaultParser.parseCompileCommand("c51 -c main.c -o main.obj"); result != nil {
+		t.Errorf("parseCompileCommand() = %v, expected nil without configured compilers", result)
+	}
+}
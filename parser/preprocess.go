@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Built-in strippers, applied in order. Prefix strippers are anchored at the start of the
+// line and are repeated so stacked prefixes ("2024-... [build] ...") are all removed.
+var builtinLineStrippers = []*regexp.Regexp{
+	// ANSI SGR colour/style sequences and erase-in-line, anywhere in the line
+	regexp.MustCompile(`\x1b\[[0-9;]*[mK]`),
+	// GitLab CI collapsible section markers: section_start:1700000000:name[collapsed=true]\r
+	regexp.MustCompile(`^section_(start|end):\d+:[^\r\s]*\r?`),
+	// ISO 8601 timestamps as written by GitHub Actions and many log collectors, including
+	// the stream marker ("00O", "01E+") that follows them in GitLab job logs
+	regexp.MustCompile(`^\[?\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?\]?(\s+\d{2}[OE]\+?)?\s+`),
+	// GitHub Actions workflow commands: ##[group], ##[endgroup], ##[command]
+	regexp.MustCompile(`^##\[[a-z]+\]`),
+	// Component tags from output-sync wrappers: [libfoo] gcc ...
+	// Progress markers such as CMake's "[ 45%]" are deliberately not matched.
+	regexp.MustCompile(`^\[[A-Za-z_][\w.:/-]*\]\s+`),
+}
+
+// newLineStrippers returns the built-in strippers followed by the user-supplied patterns
+func newLineStrippers(userPatterns []string) ([]*regexp.Regexp, error) {
+	strippers := make([]*regexp.Regexp, len(builtinLineStrippers), len(builtinLineStrippers)+len(userPatterns))
+	copy(strippers, builtinLineStrippers)
+
+	for _, pattern := range userPatterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("strip pattern %q compilation failed: %w", pattern, err)
+		}
+		strippers = append(strippers, regex)
+	}
+
+	return strippers, nil
+}
+
+// preprocessLine strips decorations from a raw log line before any recognition
+func (p *Parser) preprocessLine(line string) string {
+	for {
+		stripped := line
+		for _, stripper := range p.lineStrippers {
+			stripped = strings.TrimSpace(stripper.ReplaceAllString(stripped, ""))
+		}
+		if stripped == line {
+			return line
+		}
+		line = stripped
+	}
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestPreprocessLine(t *testing.T) {
+	parser, err := NewParser(types.ParseOptions{StripPatterns: []string{`^\d+>\s*`}})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Plain line unchanged",
+			input:    "gcc -c main.c -o main.o",
+			expected: "gcc -c main.c -o main.o",
+		},
+		{
+			name:     "ANSI colour codes",
+			input:    "\x1b[1m\x1b[32mgcc\x1b[0m -c main.c -o main.o",
+			expected: "gcc -c main.c -o main.o",
+		},
+		{
+			name:     "GitHub Actions timestamp",
+			input:    "2024-01-01T00:00:00.1234567Z gcc -c main.c -o main.o",
+			expected: "gcc -c main.c -o main.o",
+		},
+		{
+			name:     "GitLab timestamp with stream marker",
+			input:    "2024-01-01T00:00:00.123456Z 01O make: Entering directory '/src'",
+			expected: "make: Entering directory '/src'",
+		},
+		{
+			name:     "GitLab section marker",
+			input:    "section_start:1700000000:build_script\r\x1b[0Kgcc -c main.c",
+			expected: "gcc -c main.c",
+		},
+		{
+			name:     "GitHub Actions command",
+			input:    "##[group]Run make",
+			expected: "Run make",
+		},
+		{
+			name:     "Stacked timestamp and component tag",
+			input:    "[2024-01-01 00:00:00] [libfoo] gcc -c foo.c",
+			expected: "gcc -c foo.c",
+		},
+		{
+			name:     "CMake progress marker kept",
+			input:    "[ 45%] Building C object src/CMakeFiles/app.dir/main.c.o",
+			expected: "[ 45%] Building C object src/CMakeFiles/app.dir/main.c.o",
+		},
+		{
+			name:     "User pattern",
+			input:    "12> gcc -c main.c",
+			expected: "gcc -c main.c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parser.preprocessLine(tt.input)
+			if result != tt.expected {
+				t.Errorf("preprocessLine(%q) = %q, expected %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewParserInvalidStripPattern(t *testing.T) {
+	if _, err := NewParser(types.ParseOptions{StripPatterns: []string{"("}}); err == nil {
+		t.Errorf("NewParser() expected error for invalid strip pattern")
+	}
+}
+
+func TestParseMakeLogWithDecoratedLines(t *testing.T) {
+	makeLog := "2024-01-01T00:00:00Z make: Entering directory '/project'\n" +
+		"2024-01-01T00:00:01Z [app] \x1b[32mgcc -c main.c -o main.o\x1b[0m\n" +
+		"2024-01-01T00:00:02Z make: Leaving directory '/project'\n"
+
+	parser, err := NewParser(types.ParseOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	entries, err := parser.ParseMakeLog(strings.NewReader(makeLog))
+	if err != nil {
+		t.Fatalf("ParseMakeLog() failed: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Parsed %d entries, expected 1", len(entries))
+	}
+	if entries[0].WorkingDir != "/project" || entries[0].SourceFile != "main.c" {
+		t.Errorf("Entry = %+v, expected main.c in /project", entries[0])
+	}
+}
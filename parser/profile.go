@@ -0,0 +1,374 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gerryqd/yacd/utils/pathutil"
+)
+
+// CompilerProfile describes the command-line conventions of a compiler family: how a
+// command line is split into arguments, how the source and output files are found,
+// which options take values, and how its include and define options are written for clang
+type CompilerProfile struct {
+	// Name of the profile
+	Name string
+
+	// Executable names, a regular expression matched against the lower-case base name
+	// without ".exe"; empty for the GCC fallback profile
+	Executables string
+
+	// Split splits a command line into arguments
+	Split func(line string) []string
+
+	// Whether options longer than two characters take attached values (/Fobuild\)
+	AttachedValues bool
+
+	// Options whose value is an include directory, attached or as the next argument
+	IncludeOptions []string
+
+	// Options whose value is the output file, attached or as the next argument
+	OutputOptions []string
+
+	// Options whose value is the source file, attached or as the next argument
+	SourceOptions []string
+
+	// Options whose value is any other path
+	PathOptions []string
+
+	// Options taking a value that is never the source file
+	ValueOptions []string
+
+	// Options rewritten to their clang equivalent in the recorded arguments
+	Translations []OptionTranslation
+
+	// Compiled Executables pattern
+	executableRegex *regexp.Regexp
+}
+
+// OptionTranslation rewrites a compiler option to the clang option with the same meaning
+type OptionTranslation struct {
+	// Option as written for the compiler
+	From string
+
+	// Equivalent clang option
+	To string
+
+	// Whether the value may also be a keyword; the option is then only translated when
+	// its value names a header file and dropped otherwise
+	HeaderOnly bool
+}
+
+// GCCProfile parses GCC and Clang style command lines; it is used for every compiler
+// that no other profile claims
+var GCCProfile = &CompilerProfile{
+	Name:           "gcc",
+	Split:          SplitCommandLine,
+	IncludeOptions: []string{"-isystem", "-iquote", "-idirafter", "-I"},
+	OutputOptions:  []string{"-o"},
+	PathOptions:    []string{"-include", "-imacros", "--sysroot", "-isysroot", "-MF", "-MT", "-MQ"},
+}
+
+// MSVCProfile parses cl.exe and clang-cl style command lines, where options start with
+// "/" or "-" and take their values attached (/Fobuild\main.obj, /Iinclude)
+var MSVCProfile = &CompilerProfile{
+	Name:           "msvc",
+	Executables:    `cl|clang-cl`,
+	Split:          SplitWindowsCommandLine,
+	AttachedValues: true,
+	IncludeOptions: []string{"/external:I", "-external:I", "/I", "-I"},
+	OutputOptions:  []string{"/Fo:", "-Fo:", "/Fo", "-Fo"},
+	SourceOptions:  []string{"/Tp", "-Tp", "/Tc", "-Tc"},
+	PathOptions:    []string{"/FI", "-FI", "/Fd", "-Fd", "/Fp", "-Fp", "/Fa", "-Fa", "/Fe", "-Fe", "/Fi", "-Fi", "/Yu", "-Yu", "/Yc", "-Yc"},
+	ValueOptions:   []string{"/D", "-D", "/U", "-U", "/Fm", "-Fm", "/FR", "-FR", "/Fr", "-Fr"},
+}
+
+// Profiles of proprietary embedded compilers
+var (
+	// IARProfile parses IAR Embedded Workbench compilers (iccarm, iccavr, icc8051, ...)
+	IARProfile = &CompilerProfile{
+		Name:           "iar",
+		Executables:    `icc[a-z0-9]+`,
+		Split:          SplitCommandLine,
+		IncludeOptions: []string{"-I"},
+		OutputOptions:  []string{"--output", "-o"},
+		PathOptions:    []string{"--preinclude", "--dlib_config", "--dependencies", "--header_context", "-f", "-lA", "-lB", "-lC", "-lCN", "-lD"},
+		ValueOptions:   []string{"-D", "--cpu", "--fpu", "--endian", "--core", "--code_model", "--data_model", "--diag_suppress", "--diag_error", "--diag_warning", "--diag_remark", "--silicon_revision"},
+		Translations: []OptionTranslation{
+			{From: "--preinclude", To: "-include"},
+			// --dlib_config also takes the keywords none, normal and full
+			{From: "--dlib_config", To: "-include", HeaderOnly: true},
+		},
+	}
+
+	// ARMCCProfile parses the Keil ARM Compiler 5 (armcc); armclang is a clang and uses the GCC profile
+	ARMCCProfile = &CompilerProfile{
+		Name:           "armcc",
+		Executables:    `armcc`,
+		Split:          SplitCommandLine,
+		IncludeOptions: []string{"-isystem", "-I", "-J"},
+		OutputOptions:  []string{"-o"},
+		PathOptions:    []string{"--preinclude", "--depend", "--via", "--list", "--omf_browse"},
+		ValueOptions:   []string{"-D", "--cpu", "--fpu", "--apcs", "--diag_suppress", "--diag_error", "--diag_warning", "--feedback"},
+		Translations: []OptionTranslation{
+			{From: "--preinclude", To: "-include"},
+			{From: "-J", To: "-isystem"},
+		},
+	}
+
+	// TIProfile parses the TI code generation tools (armcl, cl430, cl2000, cl6x, clpru)
+	TIProfile = &CompilerProfile{
+		Name:           "ti",
+		Executables:    `armcl|cl430|cl2000|cl6x|cl7x|clpru`,
+		Split:          SplitCommandLine,
+		IncludeOptions: []string{"--include_path", "-I"},
+		OutputOptions:  []string{"--output_file", "-fe"},
+		PathOptions:    []string{"--preinclude", "--obj_directory", "-fr", "--cmd_file", "-@", "--asm_directory", "--list_directory", "--preproc_dependency"},
+		ValueOptions:   []string{"--define", "-D", "--undefine", "-U", "--silicon_version", "-v", "--abi", "--opt_level", "--diag_suppress", "--diag_warning", "--diag_error"},
+		Translations: []OptionTranslation{
+			{From: "--include_path", To: "-I"},
+			{From: "--define", To: "-D"},
+			{From: "--undefine", To: "-U"},
+			{From: "--preinclude", To: "-include"},
+		},
+	}
+
+	// SDCCProfile parses the Small Device C Compiler
+	SDCCProfile = &CompilerProfile{
+		Name:           "sdcc",
+		Executables:    `sdcc`,
+		Split:          SplitCommandLine,
+		IncludeOptions: []string{"-I"},
+		OutputOptions:  []string{"-o"},
+		ValueOptions:   []string{"-D", "--code-loc", "--code-size", "--xram-loc", "--xram-size", "--iram-size", "--stack-loc", "--data-loc", "--idata-loc"},
+	}
+
+	// XC8Profile parses the Microchip MPLAB XC8 compilers (xc8-cc and the legacy xc8 driver)
+	XC8Profile = &CompilerProfile{
+		Name:           "xc8",
+		Executables:    `xc8|xc8-cc`,
+		Split:          SplitCommandLine,
+		IncludeOptions: []string{"-I"},
+		OutputOptions:  []string{"-o"},
+		PathOptions:    []string{"--outdir", "--objdir"},
+		ValueOptions:   []string{"-D"},
+	}
+)
+
+// Profiles lists the compiler profiles tried in order before falling back to GCCProfile
+var Profiles = []*CompilerProfile{MSVCProfile, IARProfile, ARMCCProfile, TIProfile, SDCCProfile, XC8Profile}
+
+func init() {
+	for _, profile := range Profiles {
+		profile.executableRegex = regexp.MustCompile(`^(?:` + profile.Executables + `)$`)
+	}
+}
+
+// profileCompilers returns a pattern matching the executables of every profile as a whole word,
+// since short names such as "cl" appear in many other words
+func profileCompilers() string {
+	names := make([]string, len(Profiles))
+	for i, profile := range Profiles {
+		names[i] = profile.Executables
+	}
+	return `(?i:(?:^|[\s\\/"])(?:` + strings.Join(names, "|") + `)(?:\.exe)?(?:[\s"]|$))`
+}
+
+// DetectProfile returns the profile of a compiler executable, or GCCProfile when no
+// other profile claims it
+func DetectProfile(compiler string) *CompilerProfile {
+	name := strings.ToLower(compiler)
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, ".exe")
+
+	for _, profile := range Profiles {
+		if profile.executableRegex.MatchString(name) {
+			return profile
+		}
+	}
+	return GCCProfile
+}
+
+// DetectArgsProfile returns the profile of a command, also honouring clang's --driver-mode=cl
+func DetectArgsProfile(args []string) *CompilerProfile {
+	if len(args) == 0 {
+		return GCCProfile
+	}
+	for _, arg := range args[1:] {
+		if arg == "--driver-mode=cl" {
+			return MSVCProfile
+		}
+	}
+	return DetectProfile(args[0])
+}
+
+// ExtractFiles returns the source and output file of a command. The last source file
+// wins, as with the GCC driver; options naming other paths are skipped.
+func (p *CompilerProfile) ExtractFiles(args []string) (sourceFile, outputFile string) {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		if option, value, separate := p.optionValue(arg, p.OutputOptions); option != "" {
+			if separate && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			outputFile = value
+			continue
+		}
+		if option, value, separate := p.optionValue(arg, p.SourceOptions); option != "" {
+			if separate && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			sourceFile = value
+			continue
+		}
+		if option, _, separate := p.optionValue(arg, p.skippedOptions()); option != "" {
+			if separate {
+				i++
+			}
+			continue
+		}
+
+		if pathutil.IsSourceFile(arg) {
+			sourceFile = arg
+		}
+	}
+
+	return sourceFile, outputFile
+}
+
+// Translate rewrites the options listed in Translations to their clang equivalents.
+// Two-character clang options get their value attached (-Iinc), longer ones take it as
+// the next argument (-include config.h).
+func (p *CompilerProfile) Translate(args []string) []string {
+	if len(p.Translations) == 0 {
+		return args
+	}
+
+	var translated []string
+	for i := 0; i < len(args); i++ {
+		matched := false
+		for _, translation := range p.Translations {
+			option, value, separate := p.optionValue(args[i], []string{translation.From})
+			if option == "" {
+				continue
+			}
+			if separate {
+				if i+1 >= len(args) {
+					break
+				}
+				i++
+				value = args[i]
+			}
+
+			matched = true
+			if translation.HeaderOnly && !pathutil.IsHeaderFile(value) {
+				break
+			}
+			if len(translation.To) == 2 {
+				translated = append(translated, translation.To+value)
+			} else {
+				translated = append(translated, translation.To, value)
+			}
+			break
+		}
+		if !matched {
+			translated = append(translated, args[i])
+		}
+	}
+
+	return translated
+}
+
+// optionValue matches an argument against options taking a value. It returns the
+// matched option and the attached value, or separate when the value is the next argument.
+func (p *CompilerProfile) optionValue(arg string, options []string) (option, value string, separate bool) {
+	for _, option := range options {
+		switch {
+		case arg == option:
+			return option, "", true
+		case strings.HasPrefix(arg, option+"="):
+			return option, arg[len(option)+1:], false
+		// Long options only take attached values after "=", unless the profile allows it
+		case strings.HasPrefix(arg, option) && (p.AttachedValues || len(option) == 2):
+			return option, arg[len(option):], false
+		}
+	}
+	return "", "", false
+}
+
+// skippedOptions returns the options whose values are never the source or output file
+func (p *CompilerProfile) skippedOptions() []string {
+	options := make([]string, 0, len(p.IncludeOptions)+len(p.PathOptions)+len(p.ValueOptions))
+	options = append(options, p.IncludeOptions...)
+	options = append(options, p.PathOptions...)
+	return append(options, p.ValueOptions...)
+}
+
+// SplitWindowsCommandLine splits a command line the way the Microsoft C runtime does:
+// only double quotes group arguments, and backslashes are literal unless they precede
+// a double quote
+func SplitWindowsCommandLine(line string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes := false
+	hasArg := false
+	backslashes := 0
+
+	flush := func() {
+		if hasArg {
+			args = append(args, current.String())
+		}
+		current.Reset()
+		hasArg = false
+	}
+
+	for _, char := range line {
+		switch {
+		case char == '\\':
+			backslashes++
+			hasArg = true
+			continue
+		case char == '"':
+			current.WriteString(strings.Repeat(`\`, backslashes/2))
+			if backslashes%2 == 1 {
+				current.WriteRune('"')
+			} else {
+				inQuotes = !inQuotes
+			}
+			backslashes = 0
+			hasArg = true
+			continue
+		}
+
+		current.WriteString(strings.Repeat(`\`, backslashes))
+		backslashes = 0
+
+		if (char == ' ' || char == '\t') && !inQuotes {
+			flush()
+			continue
+		}
+		current.WriteRune(char)
+		hasArg = true
+	}
+	current.WriteString(strings.Repeat(`\`, backslashes))
+	flush()
+
+	return args
+}
+
+// profileForLine returns the profile of the command line starting with the compiler
+func profileForLine(line string) *CompilerProfile {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return GCCProfile
+	}
+	if strings.Contains(line, "--driver-mode=cl") {
+		return MSVCProfile
+	}
+	return DetectProfile(strings.Trim(fields[0], `"'`))
+}
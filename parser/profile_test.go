@@ -0,0 +1,286 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestDetectProfile(t *testing.T) {
+	tests := []struct {
+		compiler string
+		expected string
+	}{
+		{"gcc", "gcc"},
+		{"arm-none-eabi-g++", "gcc"},
+		{"cl", "msvc"},
+		{"CL.EXE", "msvc"},
+		{`C:\VS\bin\Hostx64\x64\cl.exe`, "msvc"},
+		{"clang-cl", "msvc"},
+		{"/usr/bin/clang-cl-17", "gcc"},
+		{"clang", "gcc"},
+		{"armclang", "gcc"},
+		{"iccarm.exe", "iar"},
+		{"icc8051", "iar"},
+		{"armcc", "armcc"},
+		{"/opt/ti/bin/cl430", "ti"},
+		{"armcl", "ti"},
+		{"sdcc", "sdcc"},
+		{"xc8-cc", "xc8"},
+		{"xc8", "xc8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.compiler, func(t *testing.T) {
+			if result := DetectProfile(tt.compiler).Name; result != tt.expected {
+				t.Errorf("DetectProfile(%q) = %s, expected %s", tt.compiler, result, tt.expected)
+			}
+		})
+	}
+
+	if result := DetectArgsProfile([]string{"clang", "--driver-mode=cl", "/c", "main.c"}).Name; result != "msvc" {
+		t.Errorf("DetectArgsProfile() with --driver-mode=cl = %s, expected msvc", result)
+	}
+}
+
+func TestSplitWindowsCommandLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Backslashes are literal",
+			input:    `cl /c /IC:\work\inc src\main.c`,
+			expected: []string{"cl", "/c", `/IC:\work\inc`, `src\main.c`},
+		},
+		{
+			name:     "Quoted path with spaces",
+			input:    `cl /c "/IC:\Program Files\SDK\include" main.c`,
+			expected: []string{"cl", "/c", `/IC:\Program Files\SDK\include`, "main.c"},
+		},
+		{
+			name:     "Escaped quote",
+			input:    `cl /DMSG=\"hi\" main.c`,
+			expected: []string{"cl", `/DMSG="hi"`, "main.c"},
+		},
+		{
+			name:     "Backslashes before a closing quote",
+			input:    `cl "/Fobuild\\" main.c`,
+			expected: []string{"cl", `/Fobuild\`, "main.c"},
+		},
+		{
+			name:     "Single quotes are not special",
+			input:    `cl /DNAME='x' main.c`,
+			expected: []string{"cl", `/DNAME='x'`, "main.c"},
+		},
+		{
+			name:     "Empty quoted argument",
+			input:    `cl "" main.c`,
+			expected: []string{"cl", "", "main.c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SplitWindowsCommandLine(tt.input)
+			if strings.Join(result, "|") != strings.Join(tt.expected, "|") || len(result) != len(tt.expected) {
+				t.Errorf("SplitWindowsCommandLine(%q) = %q, expected %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProfileExtractFiles(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		expectedSource string
+		expectedOutput string
+	}{
+		{
+			name:           "MSVC attached output",
+			args:           []string{"cl", "/nologo", "/c", `/Fobuild\main.obj`, `src\main.c`},
+			expectedSource: `src\main.c`,
+			expectedOutput: `build\main.obj`,
+		},
+		{
+			name:           "MSVC output with colon",
+			args:           []string{"cl", "/c", "/Fo:", "main.obj", "main.cpp"},
+			expectedSource: "main.cpp",
+			expectedOutput: "main.obj",
+		},
+		{
+			name:           "MSVC explicit C++ source",
+			args:           []string{"clang-cl", "/c", "/Tpgenerated.inl", "-Fogenerated.obj"},
+			expectedSource: "generated.inl",
+			expectedOutput: "generated.obj",
+		},
+		{
+			name:           "MSVC listing file is not a source",
+			args:           []string{"cl", "/c", "/Falisting.asm", "main.c"},
+			expectedSource: "main.c",
+			expectedOutput: "",
+		},
+		{
+			name:           "IAR options with separate values",
+			args:           []string{"iccarm", "--cpu", "Cortex-M4", "--preinclude", "board.c", "src/main.c", "-o", "obj/main.o"},
+			expectedSource: "src/main.c",
+			expectedOutput: "obj/main.o",
+		},
+		{
+			name:           "TI long options",
+			args:           []string{"cl430", "--include_path=inc", "--output_file=obj/main.obj", "--preinclude=cfg.c", "main.c"},
+			expectedSource: "main.c",
+			expectedOutput: "obj/main.obj",
+		},
+		{
+			name:           "SDCC placement option",
+			args:           []string{"sdcc", "-mmcs51", "--code-loc", "0x2000", "-c", "main.c", "-o", "main.rel"},
+			expectedSource: "main.c",
+			expectedOutput: "main.rel",
+		},
+		{
+			name:           "GCC attached output",
+			args:           []string{"gcc", "-c", "main.c", "-obuild/main.o"},
+			expectedSource: "main.c",
+			expectedOutput: "build/main.o",
+		},
+		{
+			name:           "GCC forced include is not a source",
+			args:           []string{"gcc", "-include", "prefix.c", "-c", "main.c"},
+			expectedSource: "main.c",
+			expectedOutput: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceFile, outputFile := DetectArgsProfile(tt.args).ExtractFiles(tt.args)
+			if sourceFile != tt.expectedSource {
+				t.Errorf("ExtractFiles() sourceFile = %s, expected %s", sourceFile, tt.expectedSource)
+			}
+			if outputFile != tt.expectedOutput {
+				t.Errorf("ExtractFiles() outputFile = %s, expected %s", outputFile, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestProfileTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "IAR pre-include and library configuration",
+			args:     []string{"iccarm", "--preinclude", "board.h", "--dlib_config=DLib_Config_Normal.h", "-Iinc", "-Ohz", "main.c"},
+			expected: "iccarm -include board.h -include DLib_Config_Normal.h -Iinc -Ohz main.c",
+		},
+		{
+			name:     "IAR library configuration keyword",
+			args:     []string{"iccarm", "--dlib_config", "normal", "--dlib_config=full", "-Iinc", "main.c"},
+			expected: "iccarm -Iinc main.c",
+		},
+		{
+			name:     "Keil system include directory",
+			args:     []string{"armcc", "-JC:/Keil/ARM/INC", "--preinclude=rte.h", "-c", "main.c"},
+			expected: "armcc -isystem C:/Keil/ARM/INC -include rte.h -c main.c",
+		},
+		{
+			name:     "TI include path and define",
+			args:     []string{"cl430", "--include_path=/opt/ti/include", "--define=__MSP430F5529__", "--undefine", "DEBUG", "main.c"},
+			expected: "cl430 -I/opt/ti/include -D__MSP430F5529__ -UDEBUG main.c",
+		},
+		{
+			name:     "GCC unchanged",
+			args:     []string{"gcc", "--preinclude", "x.h", "main.c"},
+			expected: "gcc --preinclude x.h main.c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := DetectArgsProfile(tt.args).Translate(tt.args)
+			if strings.Join(result, " ") != tt.expected {
+				t.Errorf("Translate() = %s, expected %s", strings.Join(result, " "), tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseEmbeddedCompileCommand(t *testing.T) {
+	parser, err := NewParser(types.ParseOptions{Directory: "/project"})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	tests := []struct {
+		line           string
+		expectedSource string
+		expectedArgs   string
+	}{
+		{
+			line:           `iccarm --cpu Cortex-M4 -e --dlib_config "C:/IAR/arm/inc/c/DLib_Config_Normal.h" -Iinc src/main.c -o obj`,
+			expectedSource: "src/main.c",
+			expectedArgs:   "iccarm --cpu Cortex-M4 -e -include C:/IAR/arm/inc/c/DLib_Config_Normal.h -Iinc src/main.c -o obj",
+		},
+		{
+			line:           "/opt/ti/bin/cl430 -vmspx --include_path=inc --define=DEBUG --output_file=obj/main.obj main.c",
+			expectedSource: "main.c",
+			expectedArgs:   "/opt/ti/bin/cl430 -vmspx -Iinc -DDEBUG --output_file=obj/main.obj main.c",
+		},
+		{
+			line:           "sdcc -mmcs51 --model-small -c src/main.c -o build/main.rel",
+			expectedSource: "src/main.c",
+			expectedArgs:   "sdcc -mmcs51 --model-small -c src/main.c -o build/main.rel",
+		},
+		{
+			line:           "xc8-cc -mcpu=16F877A -c main.c -o main.p1",
+			expectedSource: "main.c",
+			expectedArgs:   "xc8-cc -mcpu=16F877A -c main.c -o main.p1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			result := parser.parseCompileCommand(tt.line)
+			if result == nil {
+				t.Fatal("parseCompileCommand() = nil, expected non-nil")
+			}
+			if result.SourceFile != tt.expectedSource {
+				t.Errorf("SourceFile = %s, expected %s", result.SourceFile, tt.expectedSource)
+			}
+			if strings.Join(result.Args, " ") != tt.expectedArgs {
+				t.Errorf("Args = %s, expected %s", strings.Join(result.Args, " "), tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestParseMSVCCompileCommand(t *testing.T) {
+	parser, err := NewParser(types.ParseOptions{Directory: `C:\work`})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	result := parser.parseCompileCommand(`cl.exe /nologo /c /W4 /IC:\work\inc "/DVERSION=\"1.0\"" /Fobuild\main.obj src\main.c`)
+	if result == nil {
+		t.Fatal("parseCompileCommand() = nil, expected non-nil")
+	}
+	if result.SourceFile != `src\main.c` || result.OutputFile != `build\main.obj` {
+		t.Errorf("Files = %s, %s, expected src\\main.c, build\\main.obj", result.SourceFile, result.OutputFile)
+	}
+	if result.Args[4] != `/IC:\work\inc` || result.Args[5] != `/DVERSION="1.0"` {
+		t.Errorf("Args = %q, expected Windows tokenisation", result.Args)
+	}
+
+	// Words merely containing "cl" are not compilers
+	for _, line := range []string{"rm -f include/config.c", "make clean", "echo cl main.c"} {
+		if result := parser.parseCompileCommand(line); result != nil {
+			t.Errorf("parseCompileCommand(%q) = %v, expected nil", line, result)
+		}
+	}
+}
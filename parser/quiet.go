@@ -0,0 +1,78 @@
+package parser
+
+import "regexp"
+
+// QuietBuild describes build output that hides the compiler command lines
+type QuietBuild struct {
+	// Build system whose quiet output was recognised
+	Name string
+
+	// How to make the build print its commands
+	Hint string
+
+	// First quiet line seen and its line number
+	Line       string
+	LineNumber int
+
+	// Number of quiet lines seen
+	Count int
+}
+
+// quietStyle is a recognisable quiet output format
+type quietStyle struct {
+	name  string
+	regex *regexp.Regexp
+	hint  string
+}
+
+// Quiet output formats, tried in order on lines that are not compile commands
+var quietStyles = []quietStyle{
+	{
+		// "CC      init/main.o", "CC [M]  drivers/foo.o", "CXX      libfoo_la-bar.lo"
+		name:  "Kbuild or automake silent rules",
+		regex: regexp.MustCompile(`^(CC|CXX|AS|CCAS|CPP|HOSTCC|HOSTCXX)(\s+\[M\])?\s+\S+\.(o|lo|obj|s|i)$`),
+		hint:  "run make with V=1, or AM_DEFAULT_VERBOSITY=1 for automake projects",
+	},
+	{
+		// "[ 45%] Building C object src/CMakeFiles/app.dir/main.c.o"
+		name:  "CMake Makefiles",
+		regex: regexp.MustCompile(`^\[\s*\d+%\]\s+Building \w+ object `),
+		hint:  "run make with VERBOSE=1, or configure with -DCMAKE_VERBOSE_MAKEFILE=ON",
+	},
+	{
+		// "[12/345] Building C object ...", "[3/10] Compiling C object ...", "[3/10] CC foo.o"
+		name:  "ninja",
+		regex: regexp.MustCompile(`^\[\d+/\d+\]\s+(Building|Compiling|CC|CXX|AS)\s`),
+		hint:  "run ninja with -v",
+	},
+}
+
+// recordQuietLine counts a line that matches a quiet output format
+func (p *Parser) recordQuietLine(line string, lineNumber int) {
+	for i, style := range quietStyles {
+		if !style.regex.MatchString(line) {
+			continue
+		}
+		if p.quietBuilds == nil {
+			p.quietBuilds = make([]QuietBuild, len(quietStyles))
+		}
+		quiet := &p.quietBuilds[i]
+		if quiet.Count == 0 {
+			*quiet = QuietBuild{Name: style.name, Hint: style.hint, Line: line, LineNumber: lineNumber}
+		}
+		quiet.Count++
+		return
+	}
+}
+
+// QuietBuild returns the quiet output format seen most often, or nil when the log
+// had no quiet lines
+func (p *Parser) QuietBuild() *QuietBuild {
+	var result *QuietBuild
+	for i := range p.quietBuilds {
+		if quiet := &p.quietBuilds[i]; quiet.Count > 0 && (result == nil || quiet.Count > result.Count) {
+			result = quiet
+		}
+	}
+	return result
+}
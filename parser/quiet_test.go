@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestQuietBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		expected string
+		count    int
+	}{
+		{
+			name:     "Kbuild",
+			log:      "  SYNC    include/config/auto.conf\n  CC      init/main.o\n  CC [M]  drivers/net/dummy.o\n  AS      arch/x86/kernel/head_64.o\n  LD      vmlinux.o\n",
+			expected: "Kbuild or automake silent rules",
+			count:    3,
+		},
+		{
+			name:     "Automake silent rules",
+			log:      "make  all-am\n  CC       src/foo.o\n  CC       lib/libbar_la-bar.lo\n  CCLD     foo\n",
+			expected: "Kbuild or automake silent rules",
+			count:    2,
+		},
+		{
+			name:     "CMake Makefiles",
+			log:      "[ 50%] Building C object CMakeFiles/app.dir/main.c.o\n[100%] Building CXX object CMakeFiles/app.dir/util.cpp.o\n[100%] Linking CXX executable app\n",
+			expected: "CMake Makefiles",
+			count:    2,
+		},
+		{
+			name:     "Ninja",
+			log:      "[1/3] Building C object CMakeFiles/app.dir/main.c.o\n[2/3] Compiling C object app.p/util.c.o\n[3/3] Linking target app\n",
+			expected: "ninja",
+			count:    2,
+		},
+		{
+			name:     "Verbose build",
+			log:      "make: Entering directory '/project'\ngcc -c main.c -o main.o\n",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewParser(types.ParseOptions{})
+			if err != nil {
+				t.Fatalf("NewParser() unexpected error: %v", err)
+			}
+			if _, err := p.ParseMakeLog(strings.NewReader(tt.log)); err != nil {
+				t.Fatalf("ParseMakeLog() unexpected error: %v", err)
+			}
+
+			quiet := p.QuietBuild()
+			if tt.expected == "" {
+				if quiet != nil {
+					t.Errorf("QuietBuild() = %+v, expected nil", quiet)
+				}
+				return
+			}
+			if quiet == nil {
+				t.Fatalf("QuietBuild() = nil, expected %s", tt.expected)
+			}
+			if quiet.Name != tt.expected || quiet.Count != tt.count {
+				t.Errorf("QuietBuild() = %s with %d lines, expected %s with %d", quiet.Name, quiet.Count, tt.expected, tt.count)
+			}
+			if quiet.Hint == "" || quiet.LineNumber == 0 {
+				t.Errorf("QuietBuild() = %+v, expected a hint and line number", quiet)
+			}
+		})
+	}
+}
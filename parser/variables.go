@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gerryqd/yacd/types"
+	"github.com/gerryqd/yacd/utils/diagutil"
+)
+
+var (
+	// Variable references and command substitutions, with make's $$ escaping:
+	// $(NAME), ${NAME}, $NAME, $(command) and `command`
+	variableRefRegex = regexp.MustCompile("\\$\\$?(?:\\(([^()]*)\\)|\\{([^{}]*)\\}|([A-Za-z_][A-Za-z0-9_]*))|`([^`]*)`")
+
+	// References left in an argument after expansion
+	unresolvedRefRegex = regexp.MustCompile(`\$\$?(?:\([^()]*\)|\{[^{}]*\}|[A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// Variables and commands that name the directory a command runs in
+var directoryReferences = map[string]bool{
+	"PWD":       true,
+	"CURDIR":    true,
+	"pwd":       true,
+	"shell pwd": true,
+}
+
+// expandVariables replaces variable references from the variable table and references
+// to the current directory. Unknown references are left for dropUnresolvedReferences;
+// unknown backtick commands are left for processBacktickSubstitution.
+func (p *Parser) expandVariables(line string) string {
+	if !strings.ContainsAny(line, "$`") {
+		return line
+	}
+
+	return variableRefRegex.ReplaceAllStringFunc(line, func(ref string) string {
+		match := variableRefRegex.FindStringSubmatch(ref)
+		name := strings.TrimSpace(match[1] + match[2] + match[3] + match[4])
+
+		if directoryReferences[name] {
+			return p.currentWorkingDirectory()
+		}
+		// Command substitutions other than pwd cannot be evaluated
+		if match[4] != "" || strings.ContainsAny(name, " \t") {
+			return ref
+		}
+		if value, ok := p.options.Variables[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// dropUnresolvedReferences removes the arguments of an entry that still contain variable
+// references, together with the option they are the value of, and warns about them.
+// It reports false when the source file itself is unresolved and the entry must be skipped.
+func (p *Parser) dropUnresolvedReferences(entry *types.MakeLogEntry, lineNumber int) bool {
+	if ref := unresolvedRefRegex.FindString(entry.SourceFile); ref != "" {
+		diagutil.Print(diagutil.Warningf(0, "line %d: skipping %s, unresolved reference %s (set it with --var)", lineNumber, entry.SourceFile, ref))
+		return false
+	}
+
+	profile := DetectArgsProfile(entry.Args)
+	options := append(profile.skippedOptions(), profile.OutputOptions...)
+	args := make([]string, 0, len(entry.Args))
+	for i, arg := range entry.Args {
+		ref := unresolvedRefRegex.FindString(arg)
+		if ref == "" || i == 0 {
+			args = append(args, arg)
+			continue
+		}
+
+		dropped := arg
+		if n := len(args); n > 1 {
+			if _, _, separate := profile.optionValue(args[n-1], options); separate {
+				dropped = args[n-1] + " " + arg
+				args = args[:n-1]
+			}
+		}
+		diagutil.Print(diagutil.Warningf(0, "line %d: dropping %s from %s, unresolved reference %s (set it with --var)", lineNumber, dropped, entry.SourceFile, ref))
+	}
+	entry.Args = args
+	if unresolvedRefRegex.MatchString(entry.OutputFile) {
+		entry.OutputFile = ""
+	}
+
+	return true
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestExpandVariables(t *testing.T) {
+	parser, err := NewParser(types.ParseOptions{
+		Directory: "/project",
+		Variables: map[string]string{"CC": "arm-none-eabi-gcc", "INCDIR": "/sdk/include"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+	parser.dirStack = []string{"/project", "/project/src"}
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"$(CC) -I${INCDIR} -c main.c", "arm-none-eabi-gcc -I/sdk/include -c main.c"},
+		{"gcc -I$$(pwd)/inc -I`pwd`/lib -c main.c", "gcc -I/project/src/inc -I/project/src/lib -c main.c"},
+		{"gcc -I$(CURDIR)/inc -I$PWD -I$(shell pwd) -c main.c", "gcc -I/project/src/inc -I/project/src -I/project/src -c main.c"},
+		{"gcc -I$(UNKNOWN) -DV=`git describe` -c main.c", "gcc -I$(UNKNOWN) -DV=`git describe` -c main.c"},
+		{"gcc -c main.c", "gcc -c main.c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if result := parser.expandVariables(tt.line); result != tt.expected {
+				t.Errorf("expandVariables() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseMakeLogUnresolvedReferences(t *testing.T) {
+	log := `make: Entering directory '/project'
+$(CC) -I $(GENDIR) -DVERSION=${VERSION} -Wall -c main.c -o $(OBJDIR)/main.o
+gcc -c $(SRCDIR)/util.c -o util.o
+`
+	parser, err := NewParser(types.ParseOptions{Variables: map[string]string{"CC": "gcc", "VERSION": "1.2"}})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	entries, err := parser.ParseMakeLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseMakeLog() unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("ParseMakeLog() returned %d entries, expected 1 (unresolved source skipped)", len(entries))
+	}
+
+	entry := entries[0]
+	if args := strings.Join(entry.Args, " "); args != "gcc -DVERSION=1.2 -Wall -c main.c" {
+		t.Errorf("Args = %s, expected unresolved arguments dropped", args)
+	}
+	if entry.OutputFile != "" {
+		t.Errorf("OutputFile = %s, expected empty for an unresolved output", entry.OutputFile)
+	}
+}
@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Limit on nested launchers, guarding against commands that wrap themselves
+const maxUnwrapDepth = 16
+
+// Compiler caches and distributors that take the compiler command as their arguments
+var compilerLaunchers = map[string]bool{
+	"ccache": true,
+	"distcc": true,
+	"icecc":  true,
+}
+
+// Shells that run a command string given with -c, or a script given as first argument
+var shells = map[string]bool{
+	"sh":   true,
+	"bash": true,
+	"dash": true,
+	"ksh":  true,
+	"zsh":  true,
+}
+
+// Options of env, nice and time that take a separate value
+var launcherValueOptions = map[string]map[string]bool{
+	"env":  {"-u": true, "--unset": true, "-C": true, "--chdir": true, "-S": true, "--split-string": true},
+	"nice": {"-n": true, "--adjustment": true},
+	"time": {"-f": true, "--format": true, "-o": true, "--output": true},
+}
+
+// Libtool options that take a separate value
+var libtoolValueOptions = map[string]bool{
+	"--mode":   true,
+	"--tag":    true,
+	"--config": true,
+}
+
+// Libtool compile mode flags that are not passed to the compiler
+var libtoolOnlyFlags = map[string]bool{
+	"-prefer-pic":     true,
+	"-prefer-non-pic": true,
+	"-shared":         true,
+	"-static":         true,
+	"-no-suppress":    true,
+	"-Xcompiler":      true,
+}
+
+// Shell variable assignments written before a command: CCACHE_DIR=/tmp/cache gcc ...
+var assignmentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// shellWord is a word of a shell command line with its position in the line
+type shellWord struct {
+	text       string
+	start, end int
+}
+
+// splitShellWords splits a POSIX shell command line like SplitCommandLine, also
+// recording where each word starts and ends
+func splitShellWords(line string) []shellWord {
+	var words []shellWord
+	var current strings.Builder
+	var quote rune
+	escaped := false
+	start := -1
+
+	for i, char := range line {
+		if start == -1 && (escaped || quote != 0 || (char != ' ' && char != '\t')) {
+			start = i
+		}
+		switch {
+		case escaped:
+			current.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+		case quote == 0 && (char == '"' || char == '\''):
+			quote = char
+		case char == quote:
+			quote = 0
+		case (char == ' ' || char == '\t') && quote == 0:
+			if start != -1 {
+				words = append(words, shellWord{text: current.String(), start: start, end: i})
+				current.Reset()
+				start = -1
+			}
+		default:
+			current.WriteRune(char)
+		}
+	}
+	if start != -1 {
+		words = append(words, shellWord{text: current.String(), start: start, end: len(line)})
+	}
+
+	return words
+}
+
+// unwrapCommand strips the launchers in front of a compiler command: sh -c '...',
+// env and shell variable assignments, nice, time, ccache, distcc, icecc, configured
+// wrappers and libtool in compile mode. Other commands are returned unchanged.
+func (p *Parser) unwrapCommand(line string) string {
+	for depth := 0; depth < maxUnwrapDepth; depth++ {
+		inner, ok := p.unwrapLauncher(strings.TrimSpace(line))
+		if !ok {
+			break
+		}
+		line = inner
+	}
+	return strings.TrimSpace(line)
+}
+
+// unwrapLauncher removes one launcher from the start of a command line
+func (p *Parser) unwrapLauncher(line string) (string, bool) {
+	words := splitShellWords(line)
+	if len(words) < 2 {
+		return line, false
+	}
+	rest := func(i int) (string, bool) {
+		if i >= len(words) {
+			return line, false
+		}
+		return line[words[i].start:], true
+	}
+
+	name := filepath.Base(words[0].text)
+	switch {
+	case assignmentRegex.MatchString(words[0].text):
+		i := 1
+		for i < len(words) && assignmentRegex.MatchString(words[i].text) {
+			i++
+		}
+		return rest(i)
+
+	case shells[name]:
+		for i := 1; i < len(words); i++ {
+			arg := words[i].text
+			if !strings.HasPrefix(arg, "-") || arg == "-" {
+				// A script such as ../libtool run by the shell
+				return rest(i)
+			}
+			if !strings.HasPrefix(arg, "--") && strings.Contains(arg[1:], "c") {
+				if i+1 < len(words) {
+					return words[i+1].text, true
+				}
+				return line, false
+			}
+		}
+		return line, false
+
+	case launcherValueOptions[name] != nil:
+		i := 1
+		for i < len(words) {
+			arg := words[i].text
+			switch {
+			case launcherValueOptions[name][arg]:
+				i += 2
+			case strings.HasPrefix(arg, "-") || (name == "env" && assignmentRegex.MatchString(arg)):
+				i++
+			default:
+				return rest(i)
+			}
+		}
+		return line, false
+
+	case compilerLaunchers[name] || p.isWrapper(words[0].text):
+		return rest(1)
+
+	case isLibtool(name):
+		return unwrapLibtool(line, words)
+	}
+
+	return line, false
+}
+
+// isLibtool reports whether an executable name is libtool, including prefixed builds
+// such as x86_64-w64-mingw32-libtool and macOS glibtool
+func isLibtool(name string) bool {
+	return name == "libtool" || name == "glibtool" || strings.HasSuffix(name, "-libtool")
+}
+
+// unwrapLibtool returns the compiler command of a libtool compile mode invocation with
+// the libtool-only flags removed and the .lo output replaced by the object it names
+func unwrapLibtool(line string, words []shellWord) (string, bool) {
+	mode := ""
+	i := 1
+	for ; i < len(words); i++ {
+		arg := words[i].text
+		if option, value, found := strings.Cut(arg, "="); found && libtoolValueOptions[option] {
+			if option == "--mode" {
+				mode = value
+			}
+			continue
+		}
+		if libtoolValueOptions[arg] {
+			if arg == "--mode" && i+1 < len(words) {
+				mode = words[i+1].text
+			}
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		// The mode may also be given as the first non-option argument
+		if mode == "" && arg == "compile" {
+			mode = "compile"
+			continue
+		}
+		break
+	}
+	if mode != "compile" || i >= len(words) {
+		return line, false
+	}
+
+	var b strings.Builder
+	last := words[i].start
+	for j := i; j < len(words); j++ {
+		word := words[j]
+		raw := line[word.start:word.end]
+		replacement, replace := raw, false
+		switch {
+		case libtoolOnlyFlags[word.text]:
+			replacement, replace = "", true
+		case strings.HasPrefix(raw, "-Wc,"):
+			replacement, replace = strings.TrimPrefix(raw, "-Wc,"), true
+		case words[j-1].text == "-o" && strings.HasSuffix(word.text, ".lo"):
+			index := strings.LastIndex(raw, ".lo")
+			replacement, replace = raw[:index]+".o"+raw[index+len(".lo"):], true
+		}
+		if replace {
+			gap := line[last:word.start]
+			if replacement == "" {
+				// Drop the separator along with a removed word
+				gap = strings.TrimRight(gap, " \t")
+			}
+			b.WriteString(gap)
+			b.WriteString(replacement)
+			last = word.end
+		}
+	}
+	b.WriteString(line[last:])
+
+	return b.String(), true
+}
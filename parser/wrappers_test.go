@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerryqd/yacd/types"
+)
+
+func TestUnwrapCommand(t *testing.T) {
+	parser, err := NewParser(types.ParseOptions{Directory: "/project", Wrappers: []string{"buildcache"}})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{
+			name:     "Libtool compile mode",
+			line:     "/bin/bash ../libtool  --tag=CC   --mode=compile gcc -DHAVE_CONFIG_H -I. -g -O2 -MT foo.lo -c -o foo.lo foo.c",
+			expected: "gcc -DHAVE_CONFIG_H -I. -g -O2 -MT foo.lo -c -o foo.o foo.c",
+		},
+		{
+			name:     "Libtool only flags",
+			line:     "libtool --mode compile --silent gcc -prefer-pic -shared -Wc,-fno-common -Xcompiler -pipe -c bar.c -o lib/bar.lo",
+			expected: "gcc -fno-common -pipe -c bar.c -o lib/bar.o",
+		},
+		{
+			name:     "Libtool link mode untouched",
+			line:     "libtool --mode=link gcc -o libfoo.la foo.lo",
+			expected: "libtool --mode=link gcc -o libfoo.la foo.lo",
+		},
+		{
+			name:     "Shell command string",
+			line:     `sh -c 'gcc -DNAME="\"x y\"" -c main.c -o main.o'`,
+			expected: `gcc -DNAME="\"x y\"" -c main.c -o main.o`,
+		},
+		{
+			name:     "Shell options before -c",
+			line:     `/bin/bash -ec "cd src && gcc -c a.c"`,
+			expected: "cd src && gcc -c a.c",
+		},
+		{
+			name:     "Env assignments and options",
+			line:     "env -u CFLAGS LANG=C CCACHE_DIR=/tmp/cache ccache gcc -c a.c",
+			expected: "gcc -c a.c",
+		},
+		{
+			name:     "Shell assignments",
+			line:     "LC_ALL=C DEPS=1 gcc -c a.c",
+			expected: "gcc -c a.c",
+		},
+		{
+			name:     "Nice and time",
+			line:     "nice -n 10 /usr/bin/time -f %e distcc icecc arm-none-eabi-gcc -c a.c",
+			expected: "arm-none-eabi-gcc -c a.c",
+		},
+		{
+			name:     "Configured wrapper",
+			line:     "/opt/bin/buildcache clang -c a.c",
+			expected: "clang -c a.c",
+		},
+		{
+			name:     "Plain compiler",
+			line:     "gcc -c a.c -o a.o",
+			expected: "gcc -c a.c -o a.o",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parser.unwrapCommand(tt.line); result != tt.expected {
+				t.Errorf("unwrapCommand() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseWrappedCompileCommand(t *testing.T) {
+	parser, err := NewParser(types.ParseOptions{Directory: "/project"})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		line       string
+		workingDir string
+		source     string
+		output     string
+		args       string
+	}{
+		{
+			name:       "Libtool",
+			line:       "/bin/bash ../libtool --tag=CC --mode=compile gcc -DPIC -prefer-pic -c -o foo.lo ../src/foo.c",
+			workingDir: "/project",
+			source:     "../src/foo.c",
+			output:     "foo.o",
+			args:       "gcc -DPIC -c -o foo.o ../src/foo.c",
+		},
+		{
+			name:       "Shell with cd chain",
+			line:       "sh -c 'cd lib && ccache gcc -c util.c -o util.o'",
+			workingDir: "/project/lib",
+			source:     "util.c",
+			output:     "util.o",
+			args:       "gcc -c util.c -o util.o",
+		},
+		{
+			name:       "Env and time",
+			line:       "time env CCACHE_DISABLE=1 ccache g++ -c app.cpp",
+			workingDir: "/project",
+			source:     "app.cpp",
+			args:       "g++ -c app.cpp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parser.parseCompileCommand(tt.line)
+			if result == nil {
+				t.Fatal("parseCompileCommand() = nil, expected non-nil")
+			}
+			if result.WorkingDir != tt.workingDir {
+				t.Errorf("WorkingDir = %s, expected %s", result.WorkingDir, tt.workingDir)
+			}
+			if result.SourceFile != tt.source || result.OutputFile != tt.output {
+				t.Errorf("Files = %s -> %s, expected %s -> %s", result.SourceFile, result.OutputFile, tt.source, tt.output)
+			}
+			if args := strings.Join(result.Args, " "); args != tt.args {
+				t.Errorf("Args = %s, expected %s", args, tt.args)
+			}
+		})
+	}
+}
@@ -37,25 +37,128 @@ type MakeLogEntry struct {
 
 	// Output file path
 	OutputFile string
+
+	// Whether the working directory had to be inferred
+	DirectoryInferred bool
+}
+
+// PathMap rewrites paths starting with From to start with To
+type PathMap struct {
+	// Path prefix as it appears in the log
+	From string `yaml:"from" toml:"from"`
+
+	// Replacement prefix
+	To string `yaml:"to" toml:"to"`
+}
+
+// FlagRule edits the compiler arguments of the entries it applies to. The actions
+// of a rule are applied in field order; the compiler itself is never removed.
+type FlagRule struct {
+	// Glob patterns of arguments to remove ("*" matches any characters)
+	Remove []string `yaml:"remove,omitempty" toml:"remove,omitempty"`
+
+	// Flags to remove together with their value (-include config.h, --param=x)
+	RemoveWithValue []string `yaml:"remove_with_value,omitempty" toml:"remove_with_value,omitempty"`
+
+	// Regular expression replaced in every argument
+	Replace string `yaml:"replace,omitempty" toml:"replace,omitempty"`
+
+	// Replacement for Replace, which may refer to groups as $1
+	With string `yaml:"with,omitempty" toml:"with,omitempty"`
+
+	// Arguments inserted right after the compiler
+	Prepend []string `yaml:"prepend,omitempty" toml:"prepend,omitempty"`
+
+	// Arguments added at the end
+	Append []string `yaml:"append,omitempty" toml:"append,omitempty"`
+
+	// Glob limiting the rule to matching source files
+	Files string `yaml:"files,omitempty" toml:"files,omitempty"`
+
+	// Glob limiting the rule to matching compilers
+	Compiler string `yaml:"compiler,omitempty" toml:"compiler,omitempty"`
 }
 
 // ParseOptions parsing options
 type ParseOptions struct {
-	// Input file path
+	// Input file path (the log being parsed when several are given)
 	InputFile string
 
+	// All input file paths after glob expansion
+	InputFiles []string
+
+	// Format of the inputs (auto, make, ninja, ninja-log or aquery), detected when empty or auto
+	InputFormat string
+
 	// Output file path
 	OutputFile string
 
 	// Make command to execute
 	MakeCommand string
 
+	// Kbuild tree whose .o.cmd files are parsed instead of a log
+	KbuildDir string
+
+	// Directory to execute the make command in
+	MakeDirectory string
+
+	// Extra environment variables for the make command (KEY=VAL)
+	MakeEnv []string
+
+	// Root directory of the make invocation (from --chdir and make -C)
+	RootDir string
+
 	// Whether to use relative paths
 	UseRelativePaths bool
 
 	// Base directory
 	BaseDir string
 
+	// Explicit working directory for entries without directory information
+	Directory string
+
+	// Whether to infer working directories when make does not print them
+	InferDirectories bool
+
+	// Additional regular expressions stripped from every log line
+	StripPatterns []string
+
+	// Whether to add entries for headers, borrowed from nearby translation units
+	InferHeaders bool
+
+	// Whether to find headers by scanning #include directives instead of directories
+	HeaderIncludeScan bool
+
+	// Additional compiler executable names to recognise
+	Compilers []string
+
+	// Launcher executables (ccache, distcc) skipped before the compiler
+	Wrappers []string
+
+	// Values of variables referenced in logs as $(NAME), ${NAME} or $NAME
+	Variables map[string]string
+
+	// Path prefix rewrites applied to directories, files and arguments
+	PathMaps []PathMap
+
+	// Whether to evaluate symbolic links in absolute paths
+	ResolveSymlinks bool
+
+	// Style drive paths are written in (posix, msys, cygwin or windows), empty to keep them as logged
+	PathStyle string
+
+	// Rules keeping only matching entries ([dir:|file:][re:]PATTERN)
+	Includes []string
+
+	// Rules removing matching entries ([dir:|file:][re:]PATTERN)
+	Excludes []string
+
+	// Compiler argument edits, applied in order
+	FlagRules []FlagRule
+
+	// Output format of the compilation database
+	OutputFormat string
+
 	// Whether to enable verbose output
 	Verbose bool
 }
@@ -0,0 +1,106 @@
+package diagutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Severity of a diagnostic
+type Severity int
+
+const (
+	// SeverityNote is informational
+	SeverityNote Severity = iota
+
+	// SeverityWarning indicates a likely problem that does not fail the run
+	SeverityWarning
+
+	// SeverityError indicates a problem that fails the run
+	SeverityError
+)
+
+// Diagnostic is a message about a compilation database entry or the run as a whole
+type Diagnostic struct {
+	// Severity of the diagnostic
+	Severity Severity
+
+	// 1-based entry index, or 0 if the diagnostic is not about a single entry
+	Entry int
+
+	// Message text
+	Message string
+}
+
+// Notef creates a note diagnostic
+func Notef(entry int, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: SeverityNote, Entry: entry, Message: fmt.Sprintf(format, args...)}
+}
+
+// Warningf creates a warning diagnostic
+func Warningf(entry int, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: SeverityWarning, Entry: entry, Message: fmt.Sprintf(format, args...)}
+}
+
+// Errorf creates an error diagnostic
+func Errorf(entry int, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: SeverityError, Entry: entry, Message: fmt.Sprintf(format, args...)}
+}
+
+// String formats the diagnostic without colours
+func (d Diagnostic) String() string {
+	return d.label() + ": " + d.text()
+}
+
+// label returns the severity label
+func (d Diagnostic) label() string {
+	switch d.Severity {
+	case SeverityError:
+		return "Error"
+	case SeverityWarning:
+		return "Warning"
+	default:
+		return "Note"
+	}
+}
+
+// color returns the ANSI colour code for the severity label
+func (d Diagnostic) color() string {
+	switch d.Severity {
+	case SeverityError:
+		return "31"
+	case SeverityWarning:
+		return "33"
+	default:
+		return "36"
+	}
+}
+
+// text returns the message with the entry suffix
+func (d Diagnostic) text() string {
+	if d.Entry > 0 {
+		return fmt.Sprintf("%s (entry %d)", d.Message, d.Entry)
+	}
+	return d.Message
+}
+
+// Fprint writes the diagnostic with a coloured severity label
+func Fprint(w io.Writer, d Diagnostic) {
+	fmt.Fprintf(w, "\033[%sm%s:\033[0m %s\n", d.color(), d.label(), d.text())
+}
+
+// Print writes the diagnostic to stdout
+func Print(d Diagnostic) {
+	Fprint(os.Stdout, d)
+}
+
+// CountErrors returns the number of error diagnostics
+func CountErrors(diagnostics []Diagnostic) int {
+	count := 0
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			count++
+		}
+	}
+	return count
+}
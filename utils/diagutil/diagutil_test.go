@@ -0,0 +1,55 @@
+package diagutil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiagnosticString(t *testing.T) {
+	tests := []struct {
+		name       string
+		diagnostic Diagnostic
+		expected   string
+	}{
+		{
+			name:       "Warning for an entry",
+			diagnostic: Warningf(3, "source file does not exist: %s", "main.c"),
+			expected:   "Warning: source file does not exist: main.c (entry 3)",
+		},
+		{
+			name:       "Error without entry",
+			diagnostic: Errorf(0, "no entries"),
+			expected:   "Error: no entries",
+		},
+		{
+			name:       "Note",
+			diagnostic: Notef(1, "directory inferred"),
+			expected:   "Note: directory inferred (entry 1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.diagnostic.String(); result != tt.expected {
+				t.Errorf("String() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFprint(t *testing.T) {
+	var buf bytes.Buffer
+	Fprint(&buf, Warningf(1, "source file does not exist: %s", "main.c"))
+
+	expected := "\033[33mWarning:\033[0m source file does not exist: main.c (entry 1)\n"
+	if buf.String() != expected {
+		t.Errorf("Fprint() = %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestCountErrors(t *testing.T) {
+	diagnostics := []Diagnostic{Errorf(1, "a"), Warningf(2, "b"), Errorf(3, "c"), Notef(0, "d")}
+	if count := CountErrors(diagnostics); count != 2 {
+		t.Errorf("CountErrors() = %d, expected 2", count)
+	}
+}
@@ -0,0 +1,109 @@
+package pathutil
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PathStyle is a way of writing paths on a Windows drive
+type PathStyle string
+
+// Supported path styles
+const (
+	// PathStylePOSIX writes drive paths as mounted by WSL: /mnt/c/work
+	PathStylePOSIX PathStyle = "posix"
+
+	// PathStyleMSYS writes drive paths as MSYS2 and Git Bash do: /c/work
+	PathStyleMSYS PathStyle = "msys"
+
+	// PathStyleCygwin writes drive paths as Cygwin does: /cygdrive/c/work
+	PathStyleCygwin PathStyle = "cygwin"
+
+	// PathStyleWindows writes native Windows paths: C:\work
+	PathStyleWindows PathStyle = "windows"
+)
+
+// PathStyles returns the names of the supported path styles
+func PathStyles() []string {
+	return []string{string(PathStylePOSIX), string(PathStyleMSYS), string(PathStyleCygwin), string(PathStyleWindows)}
+}
+
+// IsValidPathStyle reports whether style names a supported path style
+func IsValidPathStyle(style string) bool {
+	for _, name := range PathStyles() {
+		if style == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Drive path prefixes in each style; the drive letter is the first group
+var (
+	windowsDriveRegex = regexp.MustCompile(`^([A-Za-z]):(?:[\\/]|$)`)
+	cygwinDriveRegex  = regexp.MustCompile(`^/cygdrive/([A-Za-z])(?:/|$)`)
+	posixDriveRegex   = regexp.MustCompile(`^/mnt/([A-Za-z])(?:/|$)`)
+	msysDriveRegex    = regexp.MustCompile(`^/([A-Za-z])(?:/|$)`)
+)
+
+// Drive path prefixes attached to an option (-I/c/work, --sysroot=C:\sdk)
+var attachedDriveRegex = regexp.MustCompile(`^(-[A-Za-z-]*?=?)([A-Za-z]:[\\/]|/cygdrive/[A-Za-z](?:/|$)|/mnt/[A-Za-z](?:/|$)|/[A-Za-z](?:/|$))`)
+
+// IsWindowsDrivePath reports whether a path starts with a drive letter (C:\ or C:/)
+func IsWindowsDrivePath(p string) bool {
+	return windowsDriveRegex.MatchString(p)
+}
+
+// SplitDrive splits a path on a Windows drive, written in any style, into the lower-case
+// drive letter and the remaining slash-separated path
+func SplitDrive(p string) (drive, rest string, ok bool) {
+	for _, re := range []*regexp.Regexp{windowsDriveRegex, cygwinDriveRegex, posixDriveRegex, msysDriveRegex} {
+		if match := re.FindStringSubmatch(p); match != nil {
+			rest = strings.ReplaceAll(p[len(match[0]):], `\`, "/")
+			return strings.ToLower(match[1]), "/" + rest, true
+		}
+	}
+	return "", "", false
+}
+
+// ConvertPathStyle rewrites a path on a Windows drive to the given style. Paths
+// without a drive, such as /usr/include or relative paths, are returned unchanged.
+func ConvertPathStyle(p string, style PathStyle) string {
+	drive, rest, ok := SplitDrive(p)
+	if !ok {
+		return p
+	}
+
+	switch style {
+	case PathStylePOSIX:
+		return strings.TrimSuffix("/mnt/"+drive+rest, "/")
+	case PathStyleMSYS:
+		return strings.TrimSuffix("/"+drive+rest, "/")
+	case PathStyleCygwin:
+		return strings.TrimSuffix("/cygdrive/"+drive+rest, "/")
+	case PathStyleWindows:
+		return strings.ToUpper(drive) + ":" + strings.ReplaceAll(rest, "/", `\`)
+	}
+	return p
+}
+
+// ConvertArgPathStyle rewrites a compiler argument that is a drive path, or an option
+// with a drive path attached, to the given style
+func ConvertArgPathStyle(arg string, style PathStyle) string {
+	if !strings.HasPrefix(arg, "-") {
+		return ConvertPathStyle(arg, style)
+	}
+
+	match := attachedDriveRegex.FindStringSubmatchIndex(arg)
+	if match == nil {
+		return arg
+	}
+	return arg[:match[3]] + ConvertPathStyle(arg[match[3]:], style)
+}
+
+// cleanWindowsPath cleans a drive path with Windows separators on any host
+func cleanWindowsPath(p string) string {
+	cleaned := path.Clean("/" + strings.ReplaceAll(p[2:], `\`, "/"))
+	return p[:2] + strings.ReplaceAll(cleaned, "/", `\`)
+}
@@ -0,0 +1,71 @@
+package pathutil
+
+import "testing"
+
+func TestConvertPathStyle(t *testing.T) {
+	tests := []struct {
+		path     string
+		style    PathStyle
+		expected string
+	}{
+		{`C:\work\src\main.c`, PathStyleMSYS, "/c/work/src/main.c"},
+		{`C:\work\src\main.c`, PathStyleCygwin, "/cygdrive/c/work/src/main.c"},
+		{`C:\work\src\main.c`, PathStylePOSIX, "/mnt/c/work/src/main.c"},
+		{"C:/work/src", PathStyleWindows, `C:\work\src`},
+		{"/c/work/src", PathStyleWindows, `C:\work\src`},
+		{"/c/work/src", PathStyleCygwin, "/cygdrive/c/work/src"},
+		{"/cygdrive/d/sdk/include", PathStyleMSYS, "/d/sdk/include"},
+		{"/mnt/d/sdk", PathStyleWindows, `D:\sdk`},
+		{"/c", PathStyleWindows, `C:\`},
+		{`C:\`, PathStyleMSYS, "/c"},
+		{"/usr/include", PathStyleWindows, "/usr/include"},
+		{"src/main.c", PathStyleMSYS, "src/main.c"},
+		{"/cygdrive", PathStyleWindows, "/cygdrive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+string(tt.style), func(t *testing.T) {
+			if result := ConvertPathStyle(tt.path, tt.style); result != tt.expected {
+				t.Errorf("ConvertPathStyle(%q, %s) = %q, expected %q", tt.path, tt.style, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConvertArgPathStyle(t *testing.T) {
+	tests := []struct {
+		arg      string
+		style    PathStyle
+		expected string
+	}{
+		{"-I/c/work/inc", PathStyleWindows, `-IC:\work\inc`},
+		{`-IC:\work\inc`, PathStyleMSYS, "-I/c/work/inc"},
+		{"-isystem/cygdrive/c/sdk", PathStyleMSYS, "-isystem/c/sdk"},
+		{"--sysroot=/c/sdk", PathStyleWindows, `--sysroot=C:\sdk`},
+		{"-o/c/out/main.o", PathStyleCygwin, "-o/cygdrive/c/out/main.o"},
+		{`C:\work\main.c`, PathStyleMSYS, "/c/work/main.c"},
+		{"-I/usr/include", PathStyleWindows, "-I/usr/include"},
+		{"-Wall", PathStyleWindows, "-Wall"},
+		{"-std=c11", PathStyleWindows, "-std=c11"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg+" "+string(tt.style), func(t *testing.T) {
+			if result := ConvertArgPathStyle(tt.arg, tt.style); result != tt.expected {
+				t.Errorf("ConvertArgPathStyle(%q, %s) = %q, expected %q", tt.arg, tt.style, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWindowsDrivePaths(t *testing.T) {
+	if !IsAbsolutePath(`C:\work`) || !IsAbsolutePath("c:/work") {
+		t.Error("IsAbsolutePath() expected drive paths to be absolute")
+	}
+	if result := NormalizePath(`C:\work\build\..\src\\main.c`); result != `C:\work\src\main.c` {
+		t.Errorf("NormalizePath() = %q, expected %q", result, `C:\work\src\main.c`)
+	}
+	if result := ResolveRelativePath(`C:\work\build`, `..\src\main.c`); result != `C:\work\src\main.c` {
+		t.Errorf("ResolveRelativePath() = %q, expected %q", result, `C:\work\src\main.c`)
+	}
+}
@@ -2,6 +2,7 @@ package pathutil
 
 import (
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 )
@@ -25,6 +26,11 @@ func IsAbsolutePath(path string) bool {
 		return true
 	}
 
+	// Check for Windows drive paths, which may appear in logs on any host
+	if IsWindowsDrivePath(path) {
+		return true
+	}
+
 	return false
 }
 
@@ -33,6 +39,9 @@ func ResolveRelativePath(baseDir, relativePath string) string {
 	if IsAbsolutePath(relativePath) {
 		return relativePath
 	}
+	if runtime.GOOS != "windows" && IsWindowsDrivePath(baseDir) {
+		return cleanWindowsPath(baseDir + `\` + relativePath)
+	}
 	return filepath.Join(baseDir, relativePath)
 }
 
@@ -43,9 +52,33 @@ func ToRelativePath(basePath, targetPath string) (string, error) {
 
 // NormalizePath cleans and normalizes a path for consistent usage
 func NormalizePath(path string) string {
+	if runtime.GOOS != "windows" && IsWindowsDrivePath(path) {
+		return cleanWindowsPath(path)
+	}
 	return filepath.Clean(path)
 }
 
+// EvalSymlinks resolves symbolic links in a path. When the path does not exist, the
+// deepest existing parent is resolved and the remaining components are kept as given.
+func EvalSymlinks(path string) string {
+	path = NormalizePath(path)
+
+	var rest []string
+	current := path
+	for {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...)
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+		current = parent
+	}
+}
+
 // ToSlashPath converts path separators to forward slashes for consistent comparison
 func ToSlashPath(path string) string {
 	return filepath.ToSlash(path)
@@ -84,6 +117,82 @@ func IsSourceFile(filename string) bool {
 	return false
 }
 
+// IsHeaderFile checks if a file is a C/C++ header based on extension
+func IsHeaderFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	headerExts := []string{".h", ".hh", ".hpp", ".hxx", ".h++", ".inc", ".inl", ".tcc"}
+
+	for _, validExt := range headerExts {
+		if ext == validExt {
+			return true
+		}
+	}
+	return false
+}
+
+// CommonPrefixDepth returns the number of leading path components two paths share
+func CommonPrefixDepth(a, b string) int {
+	aParts := strings.Split(ToSlashPath(NormalizePath(a)), "/")
+	bParts := strings.Split(ToSlashPath(NormalizePath(b)), "/")
+
+	depth := 0
+	for depth < len(aParts) && depth < len(bParts) && aParts[depth] == bParts[depth] {
+		depth++
+	}
+	return depth
+}
+
+// MatchGlob reports whether a path matches a glob pattern. "*" and "?" do not match "/",
+// "**" matches across directories, and patterns without a leading "/" may match at any
+// directory boundary, so "third_party/*.c" matches "/src/third_party/zlib.c".
+func MatchGlob(pattern, path string) bool {
+	return GlobRegexp(pattern).MatchString(ToSlashPath(path))
+}
+
+// GlobRegexp compiles a path glob pattern, as matched by MatchGlob, to a regular
+// expression matching slash-separated paths
+func GlobRegexp(pattern string) *regexp.Regexp {
+	pattern = ToSlashPath(pattern)
+	if strings.HasPrefix(pattern, "/") {
+		return regexp.MustCompile("^" + globExpression(pattern, true) + "$")
+	}
+	return regexp.MustCompile("(^|/)" + globExpression(pattern, true) + "$")
+}
+
+// StringGlobRegexp compiles a glob pattern matched against a whole string that is not a
+// path, such as a compiler flag; "*" and "?" also match "/"
+func StringGlobRegexp(pattern string) *regexp.Regexp {
+	return regexp.MustCompile("^" + globExpression(pattern, false) + "$")
+}
+
+// globExpression translates a glob pattern to an unanchored regular expression. With
+// paths, "*" and "?" stop at "/" and "**" matches across directories.
+func globExpression(pattern string, paths bool) string {
+	star, question := ".*", "."
+	if paths {
+		star, question = "[^/]*", "[^/]"
+	}
+
+	var expr strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case paths && strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString(star)
+		case pattern[i] == '?':
+			expr.WriteString(question)
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return expr.String()
+}
+
 // GetWorkingDirectory returns current working directory or empty string on error
 func GetWorkingDirectory() string {
 	if wd, err := filepath.Abs("."); err == nil {
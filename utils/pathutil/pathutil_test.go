@@ -1,6 +1,7 @@
 package pathutil
 
 import (
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -219,6 +220,51 @@ func TestIsSourceFile(t *testing.T) {
 	}
 }
 
+func TestIsHeaderFile(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected bool
+	}{
+		{filename: "main.h", expected: true},
+		{filename: "vector.hpp", expected: true},
+		{filename: "Config.H", expected: true},
+		{filename: "table.inc", expected: true},
+		{filename: "main.c", expected: false},
+		{filename: "Makefile", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			result := IsHeaderFile(tt.filename)
+			if result != tt.expected {
+				t.Errorf("IsHeaderFile(%q) = %v, expected %v", tt.filename, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCommonPrefixDepth(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "/project/src", b: "/project/src", expected: 3},
+		{a: "/project/include", b: "/project/src", expected: 2},
+		{a: "/project/src/../lib", b: "/project/lib/hash", expected: 3},
+		{a: "/other", b: "/project", expected: 1},
+		{a: "src/a", b: "lib/a", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			result := CommonPrefixDepth(tt.a, tt.b)
+			if result != tt.expected {
+				t.Errorf("CommonPrefixDepth(%q, %q) = %d, expected %d", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestJoinPaths(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -303,6 +349,94 @@ func TestSplitPath(t *testing.T) {
 	}
 }
 
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"*.c", "/src/main.c", true},
+		{"*.c", "/src/main.cpp", false},
+		{"third_party/*", "/src/third_party/zlib.c", true},
+		{"third_party/*", "/src/third_party/zlib/inflate.c", false},
+		{"third_party/**", "/src/third_party/zlib/inflate.c", true},
+		{"/src/**/test_*.c", "/src/test_main.c", true},
+		{"/src/**/test_*.c", "/src/a/b/test_io.c", true},
+		{"/src/*.c", "/other/src/main.c", false},
+		{"party/*", "/src/third_party/zlib.c", false},
+		{"main.?", "/src/main.c", true},
+		{"lib+/x.c", "/src/lib+/x.c", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if result := MatchGlob(tt.pattern, tt.path); result != tt.expected {
+				t.Errorf("MatchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStringGlobRegexp(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{"-fprofile-*", "-fprofile-arcs", true},
+		{"-O?", "-O2", true},
+		{"-O?", "-O", false},
+		{"-I/opt/*", "-I/opt/sdk/include", true},
+		{"-std=*", "x-std=c11", false},
+		{"-D*+*", "-DA+B", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.value, func(t *testing.T) {
+			if result := StringGlobRegexp(tt.pattern).MatchString(tt.value); result != tt.expected {
+				t.Errorf("StringGlobRegexp(%q) matches %q = %v, expected %v", tt.pattern, tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEvalSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need extra privileges on Windows")
+	}
+
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp directory: %v", err)
+	}
+	real := filepath.Join(tempDir, "real")
+	if err := os.Mkdir(real, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(real, filepath.Join(tempDir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"Symlinked directory", filepath.Join(tempDir, "link"), real},
+		{"Missing file below symlink", filepath.Join(tempDir, "link", "src", "main.c"), filepath.Join(real, "src", "main.c")},
+		{"Unclean path", tempDir + "/link/../link//main.c", filepath.Join(real, "main.c")},
+		{"No symlinks", "/nonexistent/dir/main.c", "/nonexistent/dir/main.c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := EvalSymlinks(tt.path); result != tt.expected {
+				t.Errorf("EvalSymlinks(%q) = %q, expected %q", tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestEnsureDirectorySeparator(t *testing.T) {
 	tests := []struct {
 		name     string